@@ -0,0 +1,340 @@
+package rip
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/dolanor/rip/encoding"
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// readOnlyFields are the indexes of the fields of Ent with the `rip:"readonly"` struct tag
+// and of its timestamps, except the id field: it is set from the request path or by the
+// entity provider.
+func readOnlyFields[Ent any]() []int {
+	t, ok := ripreflect.StructType(reflect.TypeFor[Ent]())
+	if !ok {
+		return nil
+	}
+
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		isID := f.Name == "ID" || ripreflect.HasRIPIDField(f)
+		if f.IsExported() && !isID && ripreflect.IsReadOnly(f) {
+			fields = append(fields, i)
+		}
+	}
+
+	return fields
+}
+
+// hasReadOnlyID tells if the id field of Ent has the `rip:"readonly"` struct tag:
+// the entity provider chooses the id of the created entities.
+func hasReadOnlyID[Ent any]() bool {
+	t, ok := ripreflect.StructType(reflect.TypeFor[Ent]())
+	if !ok {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		isID := f.Name == "ID" || ripreflect.HasRIPIDField(f)
+		if isID && ripreflect.HasRIPTag(f, ripreflect.TagReadOnly) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// entityStruct returns the settable struct value of the entity pointed by ent.
+func entityStruct[Ent any](ent *Ent) (reflect.Value, bool) {
+	v := reflect.ValueOf(ent).Elem()
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+
+	return v, v.Kind() == reflect.Struct
+}
+
+// clearReadOnly zeroes the read-only fields of ent, that a client could have sent.
+func clearReadOnly[Ent any](ent *Ent, fields []int) {
+	v, ok := entityStruct(ent)
+	if !ok {
+		return
+	}
+
+	for _, i := range fields {
+		v.Field(i).SetZero()
+	}
+}
+
+// keepReadOnly sets the read-only fields of ent from the stored entity.
+func keepReadOnly[Ent any](ent *Ent, stored Ent, fields []int) {
+	v, ok := entityStruct(ent)
+	if !ok {
+		return
+	}
+
+	storedValue, ok := entityStruct(&stored)
+	if !ok {
+		return
+	}
+
+	for _, i := range fields {
+		v.Field(i).Set(storedValue.Field(i))
+	}
+}
+
+// readOnlyCreate ignores the read-only fields of the entities sent by the clients.
+func readOnlyCreate[Ent any](create createFunc[Ent]) createFunc[Ent] {
+	fields := readOnlyFields[Ent]()
+	if len(fields) == 0 {
+		return create
+	}
+
+	return func(ctx context.Context, ent Ent) (Ent, error) {
+		clearReadOnly(&ent, fields)
+		return create(ctx, ent)
+	}
+}
+
+// readOnlyUpdate keeps the read-only fields of the stored entities, whatever the clients sent.
+func readOnlyUpdate[Ent any](update updateFunc[Ent], get getFunc[Ent]) updateFunc[Ent] {
+	fields := readOnlyFields[Ent]()
+	if len(fields) == 0 {
+		return update
+	}
+
+	return func(ctx context.Context, ent Ent) (Ent, error) {
+		id, err := ripreflect.GetID(ent)
+		if err != nil {
+			return ent, err
+		}
+
+		stored, err := get(ctx, id)
+		if err != nil {
+			return ent, err
+		}
+
+		keepReadOnly(&ent, stored, fields)
+		return update(ctx, ent)
+	}
+}
+
+// readOnlyBatch ignores the read-only fields of the entities of the batch operations.
+func readOnlyBatch[Ent any](batch batchFunc[Ent], get getFunc[Ent]) batchFunc[Ent] {
+	fields := readOnlyFields[Ent]()
+	if len(fields) == 0 {
+		return batch
+	}
+
+	return func(ctx context.Context, ops []BatchOperation[Ent]) ([]BatchResult[Ent], error) {
+		for i, op := range ops {
+			switch op.Action {
+			case BatchCreate:
+				clearReadOnly(&ops[i].Entity, fields)
+			case BatchUpdate:
+				id := op.ID
+				if id == "" {
+					id, _ = ripreflect.GetID(op.Entity)
+				}
+
+				stored, err := get(ctx, id)
+				if err != nil {
+					// the batch reports the missing entity
+					continue
+				}
+				keepReadOnly(&ops[i].Entity, stored, fields)
+			}
+		}
+
+		return batch(ctx, ops)
+	}
+}
+
+// ignoreCreatedIDs ignores the ids of the entities to create in a batch if the id field of Ent
+// is read-only, as for a single creation: the entity provider chooses them.
+func ignoreCreatedIDs[Ent any](ops []BatchOperation[Ent]) error {
+	if !hasReadOnlyID[Ent]() {
+		return nil
+	}
+
+	for i, op := range ops {
+		if op.Action != BatchCreate {
+			continue
+		}
+
+		ops[i].ID = ""
+		id, _, err := ripreflect.FindEntityID(&ops[i].Entity)
+		if err != nil {
+			return err
+		}
+		id.SetZero()
+	}
+
+	return nil
+}
+
+// readOnlyFieldError rejects the update of a read-only field of an entity.
+func readOnlyFieldError(fieldPath string) Error {
+	return Error{
+		Status: http.StatusBadRequest,
+		Detail: fmt.Sprintf("field %q is read-only", fieldPath),
+		Source: ErrorSource{
+			Pointer: "/" + fieldPath,
+		},
+	}
+}
+
+// acceptEncoder returns the encoder of the accept content type, that doesn't encode the
+// write-only fields.
+func acceptEncoder(w http.ResponseWriter, accept string, editMode encoding.EditMode, cfg entityRouteConfig) outputEncoder {
+	return outputEncoder{
+		encoder: encoding.AcceptEncoder(w, accept, editMode, cfg.codecs),
+	}
+}
+
+// outputEncoder encodes the values without their fields with the `rip:"writeonly"` struct tag.
+// The values keep their type, so their custom marshalers are used.
+type outputEncoder struct {
+	encoder encoding.Encoder
+}
+
+func (e outputEncoder) Encode(v any) error {
+	return e.EncodeShaped(v, encoding.Shape{})
+}
+
+// EncodeShaped encodes v with the shape of the response, e.g. with only some fields of the entities.
+func (e outputEncoder) EncodeShaped(v any, shape encoding.Shape) error {
+	if v != nil && hasWriteOnly(reflect.TypeOf(v)) || shape.Extra != nil {
+		shape.Omit = isWriteOnly
+	}
+
+	if shape.IsZero() {
+		return e.encoder.Encode(v)
+	}
+
+	shaped, ok := e.encoder.(encoding.ShapedEncoder)
+	if ok {
+		return shaped.EncodeShaped(v, shape)
+	}
+
+	if shape.Extra != nil {
+		return Error{
+			Status: http.StatusNotAcceptable,
+			Detail: "the media type of the response can not include other entities",
+			Source: ErrorSource{
+				Parameter: "include",
+			},
+		}
+	}
+
+	return e.encoder.Encode(zeroFields(v, shape))
+}
+
+func isWriteOnly(f reflect.StructField) bool {
+	return ripreflect.HasRIPTag(f, ripreflect.TagWriteOnly)
+}
+
+// writeOnlyTypes caches if each encoded type can hold write-only fields.
+var writeOnlyTypes sync.Map
+
+// hasWriteOnly tells if the values of type t can hold write-only fields, in their nested
+// structs, slices, maps and interfaces.
+func hasWriteOnly(t reflect.Type) bool {
+	cached, ok := writeOnlyTypes.Load(t)
+	if !ok {
+		cached, _ = writeOnlyTypes.LoadOrStore(t, typeHasWriteOnly(t, map[reflect.Type]bool{}))
+	}
+
+	return cached.(bool)
+}
+
+// typeHasWriteOnly tells if the values of type t can hold write-only fields.
+// visiting are the struct types being checked, to stop in the recursive types.
+func typeHasWriteOnly(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasWriteOnly(t.Elem(), visiting)
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		if t == timeType || visiting[t] {
+			return false
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if isWriteOnly(f) || typeHasWriteOnly(f.Type, visiting) {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return false
+	}
+}
+
+// zeroFields returns a copy of the entity v, or of the list of entities v, whose fields
+// omitted or not kept by shape are zero: for the encoders that can not shape the data of
+// the values, e.g. protobuf which doesn't encode the zero values.
+func zeroFields(v any, shape encoding.Shape) any {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Slice {
+		return zeroEntityFields(value, shape).Interface()
+	}
+
+	if value.IsNil() {
+		return v
+	}
+
+	list := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+	for i := 0; i < value.Len(); i++ {
+		list.Index(i).Set(zeroEntityFields(value.Index(i), shape))
+	}
+
+	return list.Interface()
+}
+
+// zeroEntityFields returns a copy of the entity v, with only its exported fields, whose
+// fields omitted or not kept by shape are zero.
+func zeroEntityFields(v reflect.Value, shape encoding.Shape) reflect.Value {
+	isPointer := v.Kind() == reflect.Pointer
+	s := v
+	if isPointer {
+		if v.IsNil() {
+			return v
+		}
+		s = v.Elem()
+	}
+
+	if s.Kind() != reflect.Struct {
+		return v
+	}
+
+	c := reflect.New(s.Type())
+	for i := 0; i < s.NumField(); i++ {
+		f := s.Type().Field(i)
+		omitted := shape.Omit != nil && shape.Omit(f) || shape.Keep != nil && !shape.Keep(f)
+		if f.IsExported() && !omitted {
+			c.Elem().Field(i).Set(s.Field(i))
+		}
+	}
+
+	if isPointer {
+		return c
+	}
+	return c.Elem()
+}
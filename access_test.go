@@ -0,0 +1,291 @@
+package rip
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dolanor/rip/encoding/html"
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+	"github.com/dolanor/rip/encoding/xml"
+)
+
+type account struct {
+	ID       string `json:"id"`
+	Name     string `json:"name" validate:"required"`
+	Balance  int    `json:"balance" rip:"readonly" validate:"required"`
+	Password string `json:"password,omitempty" rip:"writeonly"`
+}
+
+func TestReadOnlyWriteOnly(t *testing.T) {
+	ap := newMemoryProvider(account{ID: "1", Name: "Jane", Balance: 50, Password: "secret"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/accounts/", ap, WithCodecs(jsoncodec.Codec, xml.Codec, html.NewEntityCodec("/accounts/"), html.NewEntityFormCodec("/accounts/"))))
+	mux.HandleFunc("/signup", Handle(http.MethodPost, func(ctx context.Context, a account) (account, error) {
+		return a, nil
+	}, WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	do := func(t *testing.T, method, path, contentType, accept, body string) (int, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		panicErr(t, err)
+		if body != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Accept", accept)
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		panicErr(t, err)
+
+		return resp.StatusCode, string(b)
+	}
+
+	stored := func(t *testing.T, id string) account {
+		t.Helper()
+
+		a, err := ap.Get(context.Background(), id)
+		panicErr(t, err)
+		return a
+	}
+
+	t.Run("create", func(t *testing.T) {
+		status, body := do(t, http.MethodPost, "/accounts/", "application/json", "application/json", `{"id": "2", "name": "Joe", "balance": 1000, "password": "hunter2"}`)
+		if status != http.StatusCreated {
+			t.Fatal("status code is not 201:", status, body)
+		}
+		if strings.Contains(body, "password") || strings.Contains(body, "hunter2") {
+			t.Fatal("the write-only field is in the response:", body)
+		}
+
+		a := stored(t, "2")
+		if a.Balance != 0 || a.Password != "hunter2" {
+			t.Fatal("bad stored account:", a)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		status, body := do(t, http.MethodPut, "/accounts/1", "application/json", "application/json", `{"name": "Janet", "balance": 1000, "password": "secret2"}`)
+		if status != http.StatusOK {
+			t.Fatal("status code is not 200:", status, body)
+		}
+
+		a := stored(t, "1")
+		if a.Name != "Janet" || a.Balance != 50 || a.Password != "secret2" {
+			t.Fatal("bad stored account:", a)
+		}
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		status, body := do(t, http.MethodPatch, "/accounts/1", MergePatchMimeType, "application/json", `{"balance": 1000}`)
+		if status != http.StatusOK {
+			t.Fatal("status code is not 200:", status, body)
+		}
+
+		if a := stored(t, "1"); a.Balance != 50 {
+			t.Fatal("the read-only field is patched:", a)
+		}
+	})
+
+	t.Run("update read-only field", func(t *testing.T) {
+		status, body := do(t, http.MethodPut, "/accounts/1/balance", "application/json", "application/json", `1000`)
+		if status != http.StatusBadRequest {
+			t.Fatal("status code is not 400:", status, body)
+		}
+
+		var e Error
+		err := json.Unmarshal([]byte(body), &e)
+		panicErr(t, err)
+		if e.Source.Pointer != "/balance" {
+			t.Fatal("bad error pointer:", e.Source.Pointer)
+		}
+	})
+
+	t.Run("get write-only field", func(t *testing.T) {
+		status, body := do(t, http.MethodGet, "/accounts/1/password", "", "application/json", "")
+		if status != http.StatusNotFound {
+			t.Fatal("status code is not 404:", status, body)
+		}
+	})
+
+	for _, accept := range []string{"application/json", "text/xml"} {
+		for _, path := range []string{"/accounts/1", "/accounts/"} {
+			t.Run("get "+path+" "+accept, func(t *testing.T) {
+				status, body := do(t, http.MethodGet, path, "", accept, "")
+				if status != http.StatusOK {
+					t.Fatal("status code is not 200:", status, body)
+				}
+				if !strings.Contains(body, "Jane") {
+					t.Fatal("the account is not in the response:", body)
+				}
+				if strings.Contains(strings.ToLower(body), "password") || strings.Contains(body, "secret") {
+					t.Fatal("the write-only field is in the response:", body)
+				}
+			})
+		}
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		status, body := do(t, http.MethodPost, "/accounts/_batch", "application/json", "application/json", `[{"action": "create", "entity": {"id": "3", "name": "Jim", "balance": 1000, "password": "hunter3"}}]`)
+		if status != http.StatusOK {
+			t.Fatal("status code is not 200:", status, body)
+		}
+		if strings.Contains(body, "hunter3") {
+			t.Fatal("the write-only field is in the response:", body)
+		}
+
+		if a := stored(t, "3"); a.Balance != 0 {
+			t.Fatal("the read-only field is created:", a)
+		}
+	})
+
+	t.Run("handle", func(t *testing.T) {
+		status, body := do(t, http.MethodPost, "/signup", "application/json", "application/json", `{"name": "Joe", "balance": 1000, "password": "hunter2"}`)
+		if status != http.StatusOK {
+			t.Fatal("status code is not 200:", status, body)
+		}
+
+		var a account
+		err := json.Unmarshal([]byte(body), &a)
+		panicErr(t, err)
+		if a.Balance != 0 || a.Password != "" {
+			t.Fatal("bad account:", a)
+		}
+	})
+
+	t.Run("html form", func(t *testing.T) {
+		status, body := do(t, http.MethodGet, "/accounts/1?mode=edit", "", "text/html", "")
+		if status != http.StatusOK {
+			t.Fatal("status code is not 200:", status, body)
+		}
+		if !strings.Contains(body, `name="Name"`) {
+			t.Fatal("the name input is not in the form:", body)
+		}
+		if strings.Contains(body, `name="Balance"`) || strings.Contains(body, "Password") {
+			t.Fatal("the read-only or write-only fields are in the form:", body)
+		}
+	})
+
+	t.Run("openapi", func(t *testing.T) {
+		route := NewEntityRoute("/accounts/", ap, WithCodecs(jsoncodec.Codec))
+		schema := route.OpenAPISchema().Components.Schemas["account"].Value
+
+		if !schema.Properties["balance"].Value.ReadOnly {
+			t.Fatal("balance is not read-only")
+		}
+		if !schema.Properties["password"].Value.WriteOnly {
+			t.Fatal("password is not write-only")
+		}
+		if schema.Properties["name"].Value.ReadOnly || schema.Properties["name"].Value.WriteOnly {
+			t.Fatal("name is read-only or write-only")
+		}
+	})
+}
+
+// Audited is an exported embedded struct with methods.
+type Audited struct {
+	Author string `json:"author"`
+}
+
+func (a Audited) Audit() string {
+	return "by " + a.Author
+}
+
+type credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password" rip:"writeonly"`
+}
+
+type embeddingAccount struct {
+	ID string `json:"id"`
+	Audited
+	credentials
+	Token string `json:"token" rip:"writeonly"`
+}
+
+type maskedAccount struct {
+	ID   string
+	Name string
+	PIN  string `rip:"writeonly"`
+}
+
+func (a maskedAccount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"id": a.ID, "display_name": strings.ToUpper(a.Name)})
+}
+
+func TestWriteOnlyEncoding(t *testing.T) {
+	ep := newMemoryProvider(embeddingAccount{
+		ID:          "1",
+		Audited:     Audited{Author: "ann"},
+		credentials: credentials{Login: "jane", Password: "secret"},
+		Token:       "t0k3n",
+	})
+	mp := newMemoryProvider(maskedAccount{ID: "1", Name: "Jane", PIN: "1234"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/embedding/", ep, WithCodecs(jsoncodec.Codec, xml.Codec)))
+	mux.HandleFunc(HandleEntities("/masked/", mp, WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	get := func(t *testing.T, path, accept string) string {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+		panicErr(t, err)
+		req.Header.Set("Accept", accept)
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		panicErr(t, err)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode, string(b))
+		}
+
+		return string(b)
+	}
+
+	cases := []struct {
+		name   string
+		path   string
+		accept string
+		want   []string
+	}{
+		{name: "embedded json", path: "/embedding/1", accept: "application/json", want: []string{`{"id":"1","author":"ann","login":"jane"}`}},
+		{name: "embedded json list", path: "/embedding/", accept: "application/json", want: []string{`[{"id":"1","author":"ann","login":"jane"}]`}},
+		{name: "embedded xml", path: "/embedding/1", accept: "application/xml", want: []string{"<embeddingAccount>", "<Author>ann</Author>", "<Login>jane</Login>"}},
+		{name: "embedded sparse fields", path: "/embedding/1?fields=audited", accept: "application/json", want: []string{`{"id":"1","author":"ann"}`}},
+		{name: "custom marshaler", path: "/masked/1", accept: "application/json", want: []string{`"display_name":"JANE"`}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			body := get(t, c.path, c.accept)
+			for _, want := range c.want {
+				if !strings.Contains(body, want) {
+					t.Fatalf("%q is not in the response: %s", want, body)
+				}
+			}
+
+			for _, hidden := range []string{"secret", "t0k3n", "1234"} {
+				if strings.Contains(body, hidden) {
+					t.Fatal("a write-only field is in the response:", body)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,388 @@
+package rip
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dolanor/rip/encoding"
+)
+
+// OperationStatus is the status of an [Operation].
+type OperationStatus string
+
+const (
+	// OperationPending is the status of an operation whose function is running.
+	OperationPending OperationStatus = "pending"
+	// OperationDone is the status of an operation whose function returned an output.
+	OperationDone OperationStatus = "done"
+	// OperationFailed is the status of an operation whose function returned an error.
+	OperationFailed OperationStatus = "failed"
+)
+
+// defaultOperationTTL is the time the operations are kept, if [WithOperationStore] has no TTL.
+const defaultOperationTTL = 24 * time.Hour
+
+// Operation is a function running in the background, started by [HandleAsync].
+type Operation struct {
+	XMLName xml.Name `json:"-" xml:"operation" yaml:"-" msgpack:"-"`
+
+	ID        string          `json:"id" xml:"id" yaml:"id"`
+	Status    OperationStatus `json:"status" xml:"status" yaml:"status"`
+	CreatedAt time.Time       `json:"created_at" xml:"created_at" yaml:"created_at"`
+
+	// Result is the output of the function, once it is done.
+	Result any `json:"result,omitempty" xml:"result,omitempty" yaml:"result,omitempty"`
+
+	// Error is the error of the function, once it failed.
+	Error *Error `json:"error,omitempty" xml:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// OperationStore stores the operations started by [HandleAsync], see [WithOperationStore].
+// A store persisting the operations can encode them, e.g. in JSON, with their result or error.
+type OperationStore interface {
+	// Get gets the operation with id. It returns [ErrNotFound] if there is none.
+	Get(ctx context.Context, id string) (Operation, error)
+
+	// Save creates or updates the operation, and keeps it for ttl.
+	Save(ctx context.Context, op Operation, ttl time.Duration) error
+
+	// Delete deletes the operation with id. It returns [ErrNotFound] if there is none.
+	Delete(ctx context.Context, id string) error
+}
+
+// MemoryOperationStore is an [OperationStore] keeping the operations in memory.
+// It only works for a single server instance.
+type MemoryOperationStore struct {
+	mu         sync.Mutex
+	operations map[string]memoryOperation
+}
+
+type memoryOperation struct {
+	op      Operation
+	expires time.Time
+}
+
+// NewMemoryOperationStore creates an empty [MemoryOperationStore].
+func NewMemoryOperationStore() *MemoryOperationStore {
+	return &MemoryOperationStore{
+		operations: map[string]memoryOperation{},
+	}
+}
+
+func (s *MemoryOperationStore) Get(ctx context.Context, id string) (Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.operations[id]
+	if !ok || !now().Before(stored.expires) {
+		return Operation{}, ErrNotFound
+	}
+
+	return stored.op, nil
+}
+
+func (s *MemoryOperationStore) Save(ctx context.Context, op Operation, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	for id, stored := range s.operations {
+		// the expired operations are removed, so the store doesn't grow forever
+		if !t.Before(stored.expires) {
+			delete(s.operations, id)
+		}
+	}
+
+	s.operations[op.ID] = memoryOperation{
+		op:      op,
+		expires: t.Add(ttl),
+	}
+
+	return nil
+}
+
+func (s *MemoryOperationStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.operations[id]
+	if !ok || !now().Before(stored.expires) {
+		return ErrNotFound
+	}
+
+	delete(s.operations, id)
+
+	return nil
+}
+
+// asyncOperations runs the operations of a [HandleAsync] route.
+type asyncOperations struct {
+	store OperationStore
+	ttl   time.Duration
+
+	// mu prevents an operation from being saved after it is deleted.
+	mu sync.Mutex
+
+	// running are the cancel functions of the operations running on this instance, by id.
+	running map[string]context.CancelFunc
+}
+
+// HandleAsync is like [Handle] for the long-running functions: f runs in the background, and the
+// operations running it are resources of the route at urlPath:
+//
+//	POST   /operations/    : starts f, answers 202 Accepted with the Location of the operation
+//	GET    /operations/:id : answers 202 Accepted with the pending operation, then 200 OK with the finished operation, and the output of f or its error
+//	DELETE /operations/:id : cancels the operation, and deletes it
+//
+// method is the method starting f, POST in the example above.
+// f gets a context canceled by DELETE, with the deadline of [WithTimeout] if it has one.
+// The operations are kept in the store of [WithOperationStore], in memory by default, until
+// they are deleted or expire.
+func HandleAsync[
+	Input, Output any,
+](
+	urlPath, method string, f InputOutputFunc[Input, Output],
+	options ...EntityRouteOption,
+) (path string, handler http.HandlerFunc) {
+	var cfg entityRouteConfig
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	cfg = setEntityRouteConfigDefaults(cfg)
+
+	store := cfg.operationStore
+	if store == nil {
+		store = NewMemoryOperationStore()
+	}
+
+	ttl := cfg.operationTTL
+	if ttl <= 0 {
+		ttl = defaultOperationTTL
+	}
+
+	ao := &asyncOperations{
+		store:   store,
+		ttl:     ttl,
+		running: map[string]context.CancelFunc{},
+	}
+
+	validate := newValidator[Input]()
+	readOnly := readOnlyFields[Input]()
+
+	handler = func(w http.ResponseWriter, r *http.Request) {
+		accept, err := contentNegociateBestHeaderValue(r.Header, "Accept", cfg.codecs.OrderedMimeTypes)
+		if err != nil {
+			writeError(w, accept, fmt.Errorf("bad accept header format: %w", err), cfg)
+			return
+		}
+
+		id := resID(r.URL.Path, urlPath)
+		if strings.Contains(id, "/") {
+			writeError(w, accept, ErrNotFound, cfg)
+			return
+		}
+
+		if id == "" {
+			if r.Method != method {
+				badMethodHandler(w, r, []string{method}, cfg)
+				return
+			}
+
+			req, err := decodeInput(r, readOnly, validate, cfg)
+			if err != nil {
+				writeError(w, accept, err, cfg)
+				return
+			}
+
+			op, err := startOperation(ao, r, method, f, req, cfg)
+			if err != nil {
+				writeError(w, accept, err, cfg)
+				return
+			}
+
+			location, _ := entityLocation(urlPath, op)
+			w.Header().Set("Location", location)
+			w.WriteHeader(http.StatusAccepted)
+
+			err = acceptEncoder(w, accept, encoding.EditOff, cfg).Encode(op)
+			if err != nil {
+				writeError(w, accept, fmt.Errorf("encode operation: %w", err), cfg)
+				return
+			}
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			if r.Method == http.MethodHead {
+				w = headResponseWriter{ResponseWriter: w}
+			}
+
+			handleOperation(w, r, id, accept, ao.store, cfg)
+		case http.MethodDelete:
+			err := ao.cancel(r.Context(), id)
+			if err != nil {
+				writeError(w, accept, err, cfg)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			badMethodHandler(w, r, []string{http.MethodGet, http.MethodHead, http.MethodDelete}, cfg)
+		}
+	}
+
+	if cfg.idempotency != nil {
+		handler = cfg.idempotency.handler(handler, []string{method}, cfg)
+	}
+
+	if cfg.maxBodySize > 0 {
+		handler = limitBody(handler, cfg.maxBodySize)
+	}
+
+	return urlPath, handler
+}
+
+// startOperation saves a pending operation, and runs f with req in the background.
+func startOperation[Input, Output any](ao *asyncOperations, r *http.Request, method string, f InputOutputFunc[Input, Output], req Input, cfg entityRouteConfig) (Operation, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Operation{}, fmt.Errorf("generate operation id: %w", err)
+	}
+
+	op := Operation{
+		ID:        id.String(),
+		Status:    OperationPending,
+		CreatedAt: now(),
+	}
+
+	err = ao.store.Save(r.Context(), op, ao.ttl)
+	if err != nil {
+		return Operation{}, fmt.Errorf("save operation: %w", err)
+	}
+
+	// the operation outlives the request, but keeps its values
+	ctx := context.WithoutCancel(r.Context())
+	var cancel context.CancelFunc
+	timeout := cfg.timeout(methodOperation(method))
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	ao.mu.Lock()
+	ao.running[op.ID] = cancel
+	ao.mu.Unlock()
+
+	go func() {
+		defer cancel()
+
+		finished := op
+		res, err := runOperation(ctx, f, req)
+		if err != nil {
+			e := toError(fmt.Errorf("handle: %w", err), cfg)
+			finished.Status = OperationFailed
+			finished.Error = &e
+		} else {
+			finished.Status = OperationDone
+			finished.Result = res
+		}
+
+		ao.finish(ctx, finished, cfg)
+	}()
+
+	return op, nil
+}
+
+// runOperation runs f, and converts its panics to errors: nothing recovers them in the background.
+func runOperation[Input, Output any](ctx context.Context, f InputOutputFunc[Input, Output], req Input) (res Output, err error) {
+	defer func() {
+		p := recover()
+		if p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return f(ctx, req)
+}
+
+// finish saves the finished operation, unless it was canceled.
+func (ao *asyncOperations) finish(ctx context.Context, op Operation, cfg entityRouteConfig) {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+
+	_, ok := ao.running[op.ID]
+	if !ok {
+		// the operation is canceled and deleted
+		return
+	}
+	delete(ao.running, op.ID)
+
+	// the context of the operation may be done, the operation is saved anyway
+	err := ao.store.Save(context.WithoutCancel(ctx), op, ao.ttl)
+	if err != nil {
+		logger := cfg.logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Error("save finished operation", "id", op.ID, "error", err)
+	}
+}
+
+// cancel cancels the operation with id if it is running, and deletes it.
+func (ao *asyncOperations) cancel(ctx context.Context, id string) error {
+	ao.mu.Lock()
+	defer ao.mu.Unlock()
+
+	cancel, ok := ao.running[id]
+	if ok {
+		cancel()
+		delete(ao.running, id)
+	}
+
+	err := ao.store.Delete(ctx, id)
+	if err != nil {
+		return fmt.Errorf("delete operation: %w", err)
+	}
+
+	return nil
+}
+
+// handleOperation writes the operation with id: 202 Accepted while it is pending, then
+// 200 OK with its result or its error.
+func handleOperation(w http.ResponseWriter, r *http.Request, id, accept string, store OperationStore, cfg entityRouteConfig) {
+	op, err := store.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, accept, fmt.Errorf("get operation: %w", err), cfg)
+		return
+	}
+
+	status := http.StatusOK
+	switch op.Status {
+	case OperationFailed:
+		if op.Error == nil {
+			op.Error = &Error{Status: http.StatusInternalServerError, Detail: "the operation failed"}
+		}
+	case OperationDone:
+	default:
+		status = http.StatusAccepted
+	}
+
+	w.WriteHeader(status)
+
+	err = acceptEncoder(w, accept, encoding.EditOff, cfg).Encode(op)
+	if err != nil {
+		writeError(w, accept, fmt.Errorf("encode operation: %w", err), cfg)
+		return
+	}
+}
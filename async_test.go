@@ -0,0 +1,294 @@
+package rip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+type reportRequest struct {
+	Title string `json:"title"`
+}
+
+func TestHandleAsync(t *testing.T) {
+	release := make(chan struct{})
+	canceled := make(chan struct{})
+	generate := func(ctx context.Context, req reportRequest) (report, error) {
+		switch req.Title {
+		case "broken":
+			return report{}, errors.New("the data is broken")
+		case "endless":
+			<-ctx.Done()
+			close(canceled)
+			return report{}, ctx.Err()
+		}
+
+		<-release
+		return report{ID: "1", Title: req.Title}, nil
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleAsync("/reports/", http.MethodPost, generate, WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	do := func(t *testing.T, method, path, body string) (*http.Response, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		panicErr(t, err)
+
+		return resp, string(b)
+	}
+
+	start := func(t *testing.T, title string) string {
+		t.Helper()
+
+		resp, body := do(t, http.MethodPost, "/reports/", `{"title": "`+title+`"}`)
+		if resp.StatusCode != http.StatusAccepted {
+			t.Fatal("status code is not 202:", resp.StatusCode, body)
+		}
+
+		var op Operation
+		err := json.Unmarshal([]byte(body), &op)
+		panicErr(t, err)
+		if op.Status != OperationPending || resp.Header.Get("Location") != "/reports/"+op.ID {
+			t.Fatal("bad started operation:", resp.Header.Get("Location"), body)
+		}
+
+		return resp.Header.Get("Location")
+	}
+
+	// wait gets the operation at location until it is finished.
+	wait := func(t *testing.T, location string) (*http.Response, string) {
+		t.Helper()
+
+		for i := 0; i < 100; i++ {
+			resp, body := do(t, http.MethodGet, location, "")
+			if resp.StatusCode != http.StatusAccepted {
+				return resp, body
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		t.Fatal("the operation is not finished")
+		return nil, ""
+	}
+
+	t.Run("done", func(t *testing.T) {
+		location := start(t, "sales")
+
+		resp, body := do(t, http.MethodGet, location, "")
+		if resp.StatusCode != http.StatusAccepted || !strings.Contains(body, `"pending"`) {
+			t.Fatal("the operation is not pending:", resp.StatusCode, body)
+		}
+
+		close(release)
+
+		resp, body = wait(t, location)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode, body)
+		}
+
+		var op struct {
+			Status OperationStatus `json:"status"`
+			Result report          `json:"result"`
+		}
+		err := json.Unmarshal([]byte(body), &op)
+		panicErr(t, err)
+		if op.Status != OperationDone || op.Result.Title != "sales" {
+			t.Fatal("bad result:", body)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		resp, body := wait(t, start(t, "broken"))
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode, body)
+		}
+
+		var op Operation
+		err := json.Unmarshal([]byte(body), &op)
+		panicErr(t, err)
+		if op.Status != OperationFailed || op.Error == nil || op.Error.Status != http.StatusInternalServerError || !strings.Contains(op.Error.Detail, "the data is broken") {
+			t.Fatal("bad failed operation:", body)
+		}
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		location := start(t, "endless")
+
+		resp, body := do(t, http.MethodDelete, location, "")
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatal("status code is not 204:", resp.StatusCode, body)
+		}
+
+		select {
+		case <-canceled:
+		case <-time.After(time.Second):
+			t.Fatal("the operation is not canceled")
+		}
+
+		resp, _ = do(t, http.MethodGet, location, "")
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatal("status code is not 404:", resp.StatusCode)
+		}
+	})
+
+	t.Run("bad method", func(t *testing.T) {
+		resp, _ := do(t, http.MethodGet, "/reports/", "")
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Fatal("status code is not 405:", resp.StatusCode)
+		}
+	})
+}
+
+// jsonOperationStore keeps the operations encoded in JSON, as the stores persisting them.
+type jsonOperationStore struct {
+	docs map[string][]byte
+	mu   sync.Mutex
+}
+
+func (s *jsonOperationStore) Get(ctx context.Context, id string) (Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, ok := s.docs[id]
+	if !ok {
+		return Operation{}, ErrNotFound
+	}
+
+	var op Operation
+	err := json.Unmarshal(doc, &op)
+	return op, err
+}
+
+func (s *jsonOperationStore) Save(ctx context.Context, op Operation, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	doc, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	s.docs[op.ID] = doc
+	return nil
+}
+
+func (s *jsonOperationStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.docs[id]
+	if !ok {
+		return ErrNotFound
+	}
+
+	delete(s.docs, id)
+	return nil
+}
+
+func TestHandleAsyncPersistedStore(t *testing.T) {
+	generate := func(ctx context.Context, req reportRequest) (report, error) {
+		if req.Title == "broken" {
+			return report{}, Error{Status: http.StatusConflict, Detail: "the data is broken"}
+		}
+
+		return report{ID: "1", Title: req.Title}, nil
+	}
+
+	store := &jsonOperationStore{docs: map[string][]byte{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleAsync("/reports/", http.MethodPost, generate, WithCodecs(jsoncodec.Codec), WithOperationStore(store, 0)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	// run starts an operation, and waits until it is finished in the store.
+	run := func(t *testing.T, title string) Operation {
+		t.Helper()
+
+		resp, err := s.Client().Post(s.URL+"/reports/", "application/json", strings.NewReader(`{"title": "`+title+`"}`))
+		panicErr(t, err)
+		resp.Body.Close()
+
+		id := strings.TrimPrefix(resp.Header.Get("Location"), "/reports/")
+		for i := 0; i < 100; i++ {
+			op, err := store.Get(context.Background(), id)
+			panicErr(t, err)
+			if op.Status != OperationPending {
+				return op
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		t.Fatal("the operation is not finished")
+		return Operation{}
+	}
+
+	t.Run("done", func(t *testing.T) {
+		op := run(t, "sales")
+		result, ok := op.Result.(map[string]any)
+		if op.Status != OperationDone || !ok || result["title"] != "sales" {
+			t.Fatal("the result is not persisted:", op)
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		op := run(t, "broken")
+		if op.Status != OperationFailed || op.Error == nil || op.Error.Status != http.StatusConflict || !strings.Contains(op.Error.Detail, "the data is broken") {
+			t.Fatal("the error is not persisted:", op)
+		}
+	})
+}
+
+func TestMemoryOperationStore(t *testing.T) {
+	clock := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	ctx := context.Background()
+	store := NewMemoryOperationStore()
+
+	err := store.Save(ctx, Operation{ID: "1", Status: OperationDone}, time.Hour)
+	panicErr(t, err)
+
+	clock = clock.Add(30 * time.Minute)
+	op, err := store.Get(ctx, "1")
+	panicErr(t, err)
+	if op.Status != OperationDone {
+		t.Fatal("bad operation:", op)
+	}
+
+	clock = clock.Add(time.Hour)
+	_, err = store.Get(ctx, "1")
+	var e Error
+	if !errors.As(err, &e) || e.Code != ErrorCodeNotFound {
+		t.Fatal("the expired operation is found:", err)
+	}
+
+	err = store.Save(ctx, Operation{ID: "2", Status: OperationPending}, time.Hour)
+	panicErr(t, err)
+	if len(store.operations) != 1 {
+		t.Fatal("the expired operation is not removed:", len(store.operations))
+	}
+}
@@ -0,0 +1,236 @@
+package rip
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/dolanor/rip/encoding"
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// batchPathID is the path, after the route path, of the batch endpoint: POST /entities/_batch
+const batchPathID = "_batch"
+
+// isBatchPath tells if requestPath is the batch endpoint of the route.
+func isBatchPath(urlPath, requestPath string) bool {
+	id, field := getEntityField(urlPath, requestPath)
+	return id == batchPathID && field == ""
+}
+
+// BatchAction is the action of a [BatchOperation].
+type BatchAction string
+
+const (
+	// BatchCreate creates the entity of the operation.
+	BatchCreate BatchAction = "create"
+	// BatchUpdate updates the entity of the operation.
+	BatchUpdate BatchAction = "update"
+	// BatchDelete deletes the entity with the id of the operation.
+	BatchDelete BatchAction = "delete"
+)
+
+// BatchOperation is an operation on an entity of a batch request.
+type BatchOperation[Ent any] struct {
+	XMLName xml.Name `json:"-" xml:"operation" yaml:"-" msgpack:"-"`
+
+	// Action is the operation to do on the entity.
+	Action BatchAction `json:"action" xml:"action"`
+
+	// ID is the id of the entity to update or delete.
+	// For a creation or an update, it can also be set in the entity.
+	// It is ignored for a creation if the id field is read-only.
+	ID string `json:"id,omitempty" xml:"id,omitempty"`
+
+	// Entity is the entity to create or update.
+	Entity Ent `json:"entity,omitempty" xml:"entity,omitempty"`
+}
+
+// BatchResult is the result of a [BatchOperation], at the same index in the batch response.
+type BatchResult[Ent any] struct {
+	XMLName xml.Name `json:"-" xml:"result" yaml:"-" msgpack:"-"`
+
+	// Status is the HTTP status code the operation would have had in its own request.
+	Status int `json:"status" xml:"status"`
+
+	// ID is the id of the created, updated or deleted entity.
+	ID string `json:"id,omitempty" xml:"id,omitempty"`
+
+	// Entity is the created or updated entity.
+	Entity *Ent `json:"entity,omitempty" xml:"entity,omitempty"`
+
+	// Error is the reason the operation failed.
+	Error *Error `json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// Batcher can be implemented by an [EntityProvider] that can run a batch of operations
+// in a transaction, for the batch endpoint:
+//
+//	POST /entities/_batch
+//
+// Without it, the operations are run one after the other with the [EntityProvider] methods,
+// and the ones after a failed operation are still run.
+type Batcher[Ent any] interface {
+	// Batch runs all the operations, or none of them if it returns an error.
+	// The error can point to the failed operation, e.g. with an [Error] Source.Pointer "/3".
+	Batch(ctx context.Context, ops []BatchOperation[Ent]) ([]BatchResult[Ent], error)
+}
+
+type batchFunc[Ent any] func(ctx context.Context, ops []BatchOperation[Ent]) ([]BatchResult[Ent], error)
+
+// sequentialBatch runs the batch operations one by one with the entity provider functions.
+// A failed operation doesn't stop the batch: it has an error result.
+func sequentialBatch[Ent any](create createFunc[Ent], update updateFunc[Ent], deleteFn deleteFunc, cfg entityRouteConfig) batchFunc[Ent] {
+	return func(ctx context.Context, ops []BatchOperation[Ent]) ([]BatchResult[Ent], error) {
+		results := make([]BatchResult[Ent], 0, len(ops))
+		for _, op := range ops {
+			res, err := runBatchOperation(ctx, op, create, update, deleteFn)
+			if err != nil {
+				e := toError(err, cfg)
+				res = BatchResult[Ent]{
+					Status: e.Status,
+					ID:     op.ID,
+					Error:  &e,
+				}
+			}
+
+			results = append(results, res)
+		}
+
+		return results, nil
+	}
+}
+
+func runBatchOperation[Ent any](ctx context.Context, op BatchOperation[Ent], create createFunc[Ent], update updateFunc[Ent], deleteFn deleteFunc) (BatchResult[Ent], error) {
+	switch op.Action {
+	case BatchCreate:
+		ent := op.Entity
+		if op.ID != "" {
+			err := ripreflect.SetID(&ent, op.ID)
+			if err != nil {
+				return BatchResult[Ent]{}, err
+			}
+		}
+
+		ent, err := create(ctx, ent)
+		if err != nil {
+			return BatchResult[Ent]{}, err
+		}
+
+		id, err := ripreflect.GetID(ent)
+		if err != nil {
+			return BatchResult[Ent]{}, err
+		}
+
+		return BatchResult[Ent]{Status: http.StatusCreated, ID: id, Entity: &ent}, nil
+	case BatchUpdate:
+		ent := op.Entity
+		if op.ID != "" {
+			err := ripreflect.SetID(&ent, op.ID)
+			if err != nil {
+				return BatchResult[Ent]{}, err
+			}
+		}
+
+		id, err := ripreflect.GetID(ent)
+		if err != nil {
+			return BatchResult[Ent]{}, err
+		}
+
+		if id == "" {
+			return BatchResult[Ent]{}, Error{
+				Status: http.StatusBadRequest,
+				Detail: "missing id of the entity to update",
+			}
+		}
+
+		ent, err = update(ctx, ent)
+		if err != nil {
+			return BatchResult[Ent]{}, err
+		}
+
+		return BatchResult[Ent]{Status: http.StatusOK, ID: id, Entity: &ent}, nil
+	case BatchDelete:
+		if op.ID == "" {
+			return BatchResult[Ent]{}, Error{
+				Status: http.StatusBadRequest,
+				Detail: "missing id of the entity to delete",
+			}
+		}
+
+		err := deleteFn(ctx, op.ID)
+		if err != nil && !isNotFound(err) {
+			// as DELETE /entities/:id, deleting a missing entity succeeds: it doesn't exist anymore
+			return BatchResult[Ent]{}, err
+		}
+
+		return BatchResult[Ent]{Status: http.StatusNoContent, ID: op.ID}, nil
+	default:
+		return BatchResult[Ent]{}, Error{
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("unknown batch action %q", op.Action),
+		}
+	}
+}
+
+// handleBatch runs the operations of the request body, and answers with their results.
+func handleBatch[Ent any](method string, batch batchFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, accept, contentType, err := preprocessRequest(r.Method, method, r.Header, r.URL.Path, cfg)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		ops, err := decode[[]BatchOperation[Ent]](r.Body, contentType, cfg)
+		if err != nil {
+			err = fmt.Errorf("decode batch operations: %w", err)
+
+			var e Error
+			if !errors.As(err, &e) {
+				// the body too large and the strict decoding errors have their own status
+				err = badRequestError{err}
+			}
+
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		err = ignoreCreatedIDs(ops)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		if cfg.preconditionRequired {
+			// the operations can't have an If-Match header
+			for i, op := range ops {
+				if op.Action == BatchUpdate || op.Action == BatchDelete {
+					writeError(w, accept, Error{
+						Status: http.StatusPreconditionRequired,
+						Detail: "this route requires preconditions: entities can not be updated or deleted in a batch",
+						Source: ErrorSource{
+							Pointer: "/" + strconv.Itoa(i),
+						},
+					}, cfg)
+					return
+				}
+			}
+		}
+
+		results, err := batch(r.Context(), ops)
+		if err != nil {
+			writeError(w, accept, fmt.Errorf("entity provider batch: %w", err), cfg)
+			return
+		}
+
+		err = acceptEncoder(w, accept, encoding.EditOff, cfg).Encode(results)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package rip
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+// transactionMemoryProvider runs batches in a transaction: nothing changes if an operation fails.
+type transactionMemoryProvider[Ent any] struct {
+	*memoryProvider[Ent]
+}
+
+func (tp transactionMemoryProvider[Ent]) Batch(ctx context.Context, ops []BatchOperation[Ent]) ([]BatchResult[Ent], error) {
+	backup := map[string]Ent{}
+	for k, v := range tp.mem {
+		backup[k] = v
+	}
+
+	results, err := sequentialBatch(tp.Create, providerUpdate(tp.Update), tp.Delete, entityRouteConfig{})(ctx, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, res := range results {
+		if res.Error != nil {
+			tp.mem = backup
+			res.Error.Source.Pointer = "/" + strconv.Itoa(i)
+			return nil, *res.Error
+		}
+	}
+
+	return results, nil
+}
+
+func TestBatch(t *testing.T) {
+	pp := newProductProvider()
+	tp := transactionMemoryProvider[product]{newProductProvider()}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/products/", pp, WithCodecs(jsoncodec.Codec)))
+	mux.HandleFunc(HandleEntities("/transaction/", tp, WithCodecs(jsoncodec.Codec)))
+	mux.HandleFunc(HandleEntities("/preconditions/", newProductProvider(), WithCodecs(jsoncodec.Codec), WithPreconditionRequired()))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	ops := `[
+		{"action": "create", "entity": {"id": "5", "name": "stool", "price": 20}},
+		{"action": "update", "id": "1", "entity": {"name": "armchair", "price": 80}},
+		{"action": "delete", "id": "42"},
+		{"action": "delete", "id": "2"},
+		{"action": "rename", "id": "3"}
+	]`
+
+	post := func(t *testing.T, path, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, s.URL+path, strings.NewReader(body))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		return resp
+	}
+
+	t.Run("sequential", func(t *testing.T) {
+		resp := post(t, "/products/_batch", ops)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+
+		var results []BatchResult[product]
+		err := json.NewDecoder(resp.Body).Decode(&results)
+		panicErr(t, err)
+
+		statuses := []int{http.StatusCreated, http.StatusOK, http.StatusNoContent, http.StatusNoContent, http.StatusBadRequest}
+		if len(results) != len(statuses) {
+			t.Fatal("results are not 5:", results)
+		}
+		for i, res := range results {
+			if res.Status != statuses[i] {
+				t.Errorf("result %d status is not %d: %d", i, statuses[i], res.Status)
+			}
+			if (res.Error != nil) != (res.Status >= 400) {
+				t.Errorf("result %d error does not match its status: %v", i, res.Error)
+			}
+		}
+
+		if results[1].Entity == nil || results[1].Entity.ID != "1" || results[1].Entity.Name != "armchair" {
+			t.Fatal("bad updated entity:", results[1].Entity)
+		}
+
+		_, err = pp.Get(context.Background(), "5")
+		if err != nil {
+			t.Fatal("batch did not create the entity:", err)
+		}
+		_, err = pp.Get(context.Background(), "2")
+		if err == nil {
+			t.Fatal("batch did not delete the entity")
+		}
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		resp := post(t, "/transaction/_batch", ops)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatal("status code is not 400:", resp.StatusCode)
+		}
+
+		var e Error
+		err := json.NewDecoder(resp.Body).Decode(&e)
+		panicErr(t, err)
+		if e.Source.Pointer != "/4" {
+			t.Fatal("error does not point to the failed operation:", e.Source.Pointer)
+		}
+
+		_, err = tp.Get(context.Background(), "5")
+		if err == nil {
+			t.Fatal("failed batch created an entity")
+		}
+	})
+
+	t.Run("preconditions required", func(t *testing.T) {
+		resp := post(t, "/preconditions/_batch", ops)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusPreconditionRequired {
+			t.Fatal("status code is not 428:", resp.StatusCode)
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		resp, err := s.Client().Get(s.URL + "/products/_batch")
+		panicErr(t, err)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed {
+			t.Fatal("status code is not 405:", resp.StatusCode)
+		}
+		if resp.Header.Get("Allow") != "POST, OPTIONS" {
+			t.Fatal("bad Allow header:", resp.Header.Get("Allow"))
+		}
+	})
+}
+
+type ticket struct {
+	ID    string `json:"id" rip:"readonly"`
+	Title string `json:"title"`
+}
+
+// ticketProvider chooses the ids of the created tickets.
+type ticketProvider struct {
+	*memoryProvider[ticket]
+	created int
+}
+
+func (tp *ticketProvider) Create(ctx context.Context, t ticket) (ticket, error) {
+	if t.ID == "" {
+		tp.created++
+		t.ID = "T" + strconv.Itoa(tp.created)
+	}
+
+	return tp.memoryProvider.Create(ctx, t)
+}
+
+func TestBatchReadOnlyID(t *testing.T) {
+	tp := &ticketProvider{memoryProvider: newMemoryProvider[ticket]()}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/tickets/", tp, WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	ops := `[
+		{"action": "create", "id": "admin", "entity": {"title": "login is broken"}},
+		{"action": "create", "entity": {"id": "root", "title": "logout is broken"}}
+	]`
+	resp, err := s.Client().Post(s.URL+"/tickets/_batch", "application/json", strings.NewReader(ops))
+	panicErr(t, err)
+	defer resp.Body.Close()
+
+	var results []BatchResult[ticket]
+	err = json.NewDecoder(resp.Body).Decode(&results)
+	panicErr(t, err)
+
+	if len(results) != 2 || results[0].ID != "T1" || results[1].ID != "T2" {
+		t.Fatal("the created tickets don't have the provider ids:", results)
+	}
+}
@@ -0,0 +1,93 @@
+package rip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dolanor/rip/encoding"
+)
+
+// limitedBody is a request body limited by [WithMaxBodySize].
+type limitedBody struct {
+	io.ReadCloser
+
+	limit int64
+
+	// exceeded is true once the body is read past the limit: some decoders
+	// don't keep the read error.
+	exceeded bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		b.exceeded = true
+	}
+
+	return n, err
+}
+
+// limitBody limits the size of the request bodies read by next to limit bytes.
+func limitBody(next http.HandlerFunc, limit int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		r.Body = &limitedBody{
+			ReadCloser: http.MaxBytesReader(w, r.Body, limit),
+			limit:      limit,
+		}
+
+		next(w, r)
+	}
+}
+
+// bodyTooLargeError is the error of a request body larger than limit bytes.
+func bodyTooLargeError(limit int64) Error {
+	return Error{
+		Status: http.StatusRequestEntityTooLarge,
+		Detail: fmt.Sprintf("request body is larger than %d bytes", limit),
+	}
+}
+
+// contentTypeDecoder returns the decoder of the content type, strict with [WithStrictDecoding].
+func contentTypeDecoder(r io.Reader, contentType string, cfg entityRouteConfig) (encoding.Decoder, error) {
+	if cfg.strictDecoding {
+		return encoding.StrictContentTypeDecoder(r, contentType, cfg.codecs)
+	}
+
+	return encoding.ContentTypeDecoder(r, contentType, cfg.codecs)
+}
+
+// decodeError converts the error of decoding the request body r: a body too large, or
+// rejected by a strict decoder.
+// The pointer of the unknown fields is prefixed by the pointer segments.
+func decodeError(err error, r io.Reader, pointer ...string) error {
+	lb, ok := r.(*limitedBody)
+	if ok && lb.exceeded {
+		return bodyTooLargeError(lb.limit)
+	}
+
+	var ufe encoding.UnknownFieldError
+	if errors.As(err, &ufe) {
+		path := append(pointer, ufe.Path...)
+		return Error{
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("unknown field %q", strings.Join(path, "/")),
+			Source: ErrorSource{
+				Pointer: "/" + strings.Join(path, "/"),
+			},
+		}
+	}
+
+	if errors.Is(err, encoding.ErrTrailingData) {
+		return Error{
+			Status: http.StatusBadRequest,
+			Detail: err.Error(),
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,155 @@
+package rip
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+	xmlcodec "github.com/dolanor/rip/encoding/xml"
+	yamlcodec "github.com/dolanor/rip/encoding/yaml"
+)
+
+type shipment struct {
+	ID      string          `json:"id" xml:"id" yaml:"id"`
+	Weight  int             `json:"weight" xml:"weight" yaml:"weight"`
+	Address shipmentAddress `json:"address" xml:"address" yaml:"address"`
+}
+
+type shipmentAddress struct {
+	City string `json:"city" xml:"city" yaml:"city"`
+}
+
+func TestMaxBodySize(t *testing.T) {
+	sp := newMemoryProvider[shipment]()
+	_, handler := HandleEntities("/shipments/", sp, WithCodecs(jsoncodec.Codec, yamlcodec.Codec), WithMaxBodySize(64))
+
+	tests := map[string]struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		"small json":        {"application/json", `{"id": "1", "weight": 10}`, http.StatusCreated},
+		"large json":        {"application/json", `{"id": "2", "weight": 10, "address": {"city": "` + strings.Repeat("a", 64) + `"}}`, http.StatusRequestEntityTooLarge},
+		"large yaml":        {"text/yaml", "id: \"3\"\naddress:\n  city: " + strings.Repeat("a", 64) + "\n", http.StatusRequestEntityTooLarge},
+		"large merge patch": {"application/merge-patch+json", `{"address": {"city": "` + strings.Repeat("a", 64) + `"}}`, http.StatusRequestEntityTooLarge},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			method, path := http.MethodPost, "/shipments/"
+			if strings.Contains(tc.contentType, "patch") {
+				method, path = http.MethodPatch, "/shipments/1"
+			}
+
+			req := httptest.NewRequest(method, path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status code is not %d: %d %s", tc.status, rec.Code, rec.Body)
+			}
+		})
+	}
+}
+
+func TestStrictDecoding(t *testing.T) {
+	sp := newMemoryProvider[shipment]()
+	options := []EntityRouteOption{WithCodecs(jsoncodec.Codec, xmlcodec.Codec, yamlcodec.Codec), WithStrictDecoding()}
+	_, handler := HandleEntities("/shipments/", sp, options...)
+	_, lenient := HandleEntities("/shipments/", sp, WithCodecs(jsoncodec.Codec))
+
+	// the tests are ordered: the field update needs the first shipment
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		pointer     string
+	}{
+		{
+			"json", http.MethodPost, "/shipments/", "application/json",
+			`{"id": "1", "Weight": 10, "address": {"city": "Paris"}}`,
+			http.StatusCreated, "",
+		},
+		{
+			"json unknown field", http.MethodPost, "/shipments/", "application/json",
+			`{"id": "2", "weight": 10, "address": {"city": "Paris", "zip_code": "75001"}}`,
+			http.StatusBadRequest, "/address/zip_code",
+		},
+		{
+			"json trailing data", http.MethodPost, "/shipments/", "application/json",
+			`{"id": "3", "weight": 10} {"id": "4"}`,
+			http.StatusBadRequest, "",
+		},
+		{
+			"xml unknown field", http.MethodPost, "/shipments/", "application/xml",
+			`<shipment><id>5</id><address><city>Paris</city></address><volume>3</volume></shipment>`,
+			http.StatusBadRequest, "/volume",
+		},
+		{
+			"xml trailing data", http.MethodPost, "/shipments/", "application/xml",
+			`<shipment><id>6</id></shipment> <shipment><id>7</id></shipment>`,
+			http.StatusBadRequest, "",
+		},
+		{
+			"yaml unknown field", http.MethodPost, "/shipments/", "text/yaml",
+			"id: \"8\"\naddress:\n  city: Paris\n  country: France\n",
+			http.StatusBadRequest, "/address/country",
+		},
+		{
+			"yaml trailing data", http.MethodPost, "/shipments/", "text/yaml",
+			"id: \"9\"\n---\nid: \"10\"\n",
+			http.StatusBadRequest, "",
+		},
+		{
+			"field unknown field", http.MethodPut, "/shipments/1/address", "application/json",
+			`{"city": "Lyon", "street": "rue de la République"}`,
+			http.StatusBadRequest, "/address/street",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status code is not %d: %d %s", tc.status, rec.Code, rec.Body)
+			}
+
+			if tc.pointer == "" {
+				return
+			}
+
+			var e Error
+			err := json.Unmarshal(rec.Body.Bytes(), &e)
+			panicErr(t, err)
+
+			if e.Source.Pointer != tc.pointer {
+				t.Fatalf("the error doesn't point to %s: %+v", tc.pointer, e)
+			}
+		})
+	}
+
+	t.Run("lenient", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/shipments/", strings.NewReader(`{"id": "11", "volume": 3}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		lenient(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatal("status code is not 201:", rec.Code, rec.Body)
+		}
+	})
+}
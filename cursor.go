@@ -0,0 +1,64 @@
+package rip
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// ErrInvalidCursor can be returned by a [CursorLister] if the cursor has not been created by it.
+var ErrInvalidCursor = Error{
+	Status: http.StatusBadRequest,
+	Detail: "invalid cursor",
+	Source: ErrorSource{
+		Parameter: "cursor",
+	},
+}
+
+// listCursor lists the entities at the cursor of the query. It returns the links to the next
+// and previous groups of entities.
+// It fails if the provider doesn't implement [CursorLister], or if the query has parameters
+// that don't work with cursors.
+func (l entityListers[Ent]) listCursor(r *http.Request, limit int) ([]Ent, []link, error) {
+	query := r.URL.Query()
+
+	cursorErr := func(detail, parameter string) error {
+		return Error{
+			Status: http.StatusBadRequest,
+			Detail: detail,
+			Source: ErrorSource{
+				Parameter: parameter,
+			},
+		}
+	}
+
+	if l.cursor == nil {
+		return nil, nil, cursorErr("this route can not paginate with cursors", "cursor")
+	}
+
+	if query.Has("page") {
+		return nil, nil, cursorErr("the page and cursor query parameters can not be used together", "page")
+	}
+
+	if query.Has("sort") {
+		return nil, nil, cursorErr("the entities can not be sorted with cursor pagination", "sort")
+	}
+
+	if keys := filterKeys(query); len(keys) > 0 {
+		return nil, nil, cursorErr("the entities can not be filtered with cursor pagination", keys[0])
+	}
+
+	ents, next, prev, err := l.cursor(r.Context(), query.Get("cursor"), limit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var links []link
+	if prev != "" {
+		links = append(links, queryLink(r, "prev", url.Values{"cursor": {prev}}))
+	}
+	if next != "" {
+		links = append(links, queryLink(r, "next", url.Values{"cursor": {next}}))
+	}
+
+	return ents, links, nil
+}
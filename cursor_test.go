@@ -0,0 +1,119 @@
+package rip
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+// cursorMemoryProvider paginates its entities with their IDs as cursors.
+type cursorMemoryProvider[Ent any] struct {
+	*memoryProvider[Ent]
+}
+
+func (cp cursorMemoryProvider[Ent]) ListCursor(ctx context.Context, cursor string, limit int) ([]Ent, string, string, error) {
+	all, err := cp.List(ctx, 0, len(cp.mem))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var ids []string
+	for id := range cp.mem {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	start := 0
+	if cursor != "" {
+		i, ok := slices.BinarySearch(ids, cursor)
+		if !ok {
+			return nil, "", "", ErrInvalidCursor
+		}
+		start = i
+	}
+
+	end := min(start+limit, len(all))
+	var next, prev string
+	if end < len(all) {
+		next = ids[end]
+	}
+	if start > 0 {
+		prev = ids[max(start-limit, 0)]
+	}
+
+	return all[start:end], next, prev, nil
+}
+
+func TestListCursor(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/products/", cursorMemoryProvider[product]{newProductProvider()}, WithCodecs(jsoncodec.Codec)))
+	mux.HandleFunc(HandleEntities("/paged/", newProductProvider(), WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	cases := map[string]struct {
+		path      string
+		query     string
+		status    int
+		ids       []string
+		link      string
+		parameter string
+	}{
+		"first":              {query: "?cursor=&page_size=2", status: http.StatusOK, ids: []string{"1", "2"}, link: `</products/?cursor=3&page_size=2>; rel="next"`},
+		"middle":             {query: "?cursor=2&page_size=2", status: http.StatusOK, ids: []string{"2", "3"}, link: `</products/?cursor=1&page_size=2>; rel="prev", </products/?cursor=4&page_size=2>; rel="next"`},
+		"last":               {query: "?cursor=3&page_size=2", status: http.StatusOK, ids: []string{"3", "4"}, link: `</products/?cursor=1&page_size=2>; rel="prev"`},
+		"invalid cursor":     {query: "?cursor=42", status: http.StatusBadRequest, parameter: "cursor"},
+		"with page":          {query: "?cursor=2&page=2", status: http.StatusBadRequest, parameter: "page"},
+		"with filter":        {query: "?cursor=2&status=sold", status: http.StatusBadRequest, parameter: "status"},
+		"cursor unsupported": {path: "/paged/", query: "?cursor=2", status: http.StatusBadRequest, parameter: "cursor"},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := "/products/"
+			if c.path != "" {
+				path = c.path
+			}
+
+			resp, err := s.Client().Get(s.URL + path + c.query)
+			panicErr(t, err)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != c.status {
+				t.Fatalf("status code is not %d: %d", c.status, resp.StatusCode)
+			}
+
+			if c.status != http.StatusOK {
+				var e Error
+				err = json.NewDecoder(resp.Body).Decode(&e)
+				panicErr(t, err)
+				if e.Source.Parameter != c.parameter {
+					t.Fatalf("error parameter is not %q: %q", c.parameter, e.Source.Parameter)
+				}
+				return
+			}
+
+			if resp.Header.Get("Link") != c.link {
+				t.Fatalf("Link header is not %q: %q", c.link, resp.Header.Get("Link"))
+			}
+
+			var products []product
+			err = json.NewDecoder(resp.Body).Decode(&products)
+			panicErr(t, err)
+
+			ids := []string{}
+			for _, p := range products {
+				ids = append(ids, p.ID)
+			}
+			if !reflect.DeepEqual(ids, c.ids) {
+				t.Fatalf("listed ids are not %v: %v", c.ids, ids)
+			}
+		})
+	}
+}
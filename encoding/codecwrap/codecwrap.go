@@ -14,3 +14,9 @@ func Wrap[E encoding.Encoder, EFunc func(w io.Writer) E, D encoding.Decoder, DFu
 		MimeTypes:  mimeTypes,
 	}
 }
+
+// WithStrictDecoder adds a strict decoder to codec.
+func WithStrictDecoder[D encoding.Decoder, DFunc func(r io.Reader) D](codec encoding.Codec, strictDecoderFunc DFunc) encoding.Codec {
+	codec.NewStrictDecoder = func(r io.Reader) encoding.Decoder { return strictDecoderFunc(r) }
+	return codec
+}
@@ -33,6 +33,10 @@ const DefaultCodecKey = "default_codec_key"
 
 // Register registers a new codec to the codec registry.
 func (c *Codecs) Register(codec Codec) {
+	if c.Codecs == nil {
+		c.Codecs = map[string]Codec{}
+	}
+
 	_, ok := c.Codecs[DefaultCodecKey]
 	if !ok {
 		c.Codecs[DefaultCodecKey] = codec
@@ -49,6 +53,10 @@ type Codec struct {
 	NewEncoder func(w io.Writer) Encoder
 	NewDecoder func(r io.Reader) Decoder
 	MimeTypes  []string
+
+	// NewStrictDecoder optionally creates a decoder rejecting the unknown fields,
+	// with an [UnknownFieldError], and the data after the decoded value, with [ErrTrailingData].
+	NewStrictDecoder func(r io.Reader) Decoder
 }
 
 // Decoder decodes encoded value from a input stream.
@@ -58,10 +66,10 @@ type Decoder interface {
 }
 
 // ContentTypeDecoder decodes the encoded data from r based on the Content-Type header value
-// and the codecs that are available.
+// and the codecs that are available. The parameters of the media type, e.g. charset, are ignored.
 // If no codec is found, it returns a [ErrNoEncoderAvailable].
 func ContentTypeDecoder(r io.Reader, contentTypeHeader string, codecs Codecs) (Decoder, error) {
-	decoder, ok := codecs.Codecs[contentTypeHeader]
+	decoder, ok := codecs.codec(contentTypeHeader)
 	if !ok {
 		return nil, ErrNoEncoderAvailable
 	}
@@ -69,6 +77,21 @@ func ContentTypeDecoder(r io.Reader, contentTypeHeader string, codecs Codecs) (D
 	return decoder.NewDecoder(r), nil
 }
 
+// StrictContentTypeDecoder is like [ContentTypeDecoder], but it returns the strict decoder of
+// the codec if it has one.
+func StrictContentTypeDecoder(r io.Reader, contentTypeHeader string, codecs Codecs) (Decoder, error) {
+	decoder, ok := codecs.codec(contentTypeHeader)
+	if !ok {
+		return nil, ErrNoEncoderAvailable
+	}
+
+	if decoder.NewStrictDecoder == nil {
+		return decoder.NewDecoder(r), nil
+	}
+
+	return decoder.NewStrictDecoder(r), nil
+}
+
 // Encoder writes encoded value to an output stream.
 type Encoder interface {
 	// Encode writes the codec data of v to the output stream.
@@ -2,6 +2,7 @@ package html
 
 import (
 	_ "embed"
+	"encoding/xml"
 	"fmt"
 	"html/template"
 	"io"
@@ -79,10 +80,14 @@ func NewFormEncoder(pathPrefix string, opts ...Option) func(w io.Writer) *FormEn
 }
 
 func (e FormEncoder) Encode(v interface{}) error {
-	return htmlEncode(e.pathPrefix, e.config.templatesFS, e.w, editOn, v)
+	return htmlEncode(e.pathPrefix, e.config.templatesFS, e.w, editOn, v, encoding.Shape{})
 }
 
-func htmlEncode(pathPrefix string, templatesFS fs.FS, w io.Writer, edit editMode, v interface{}) error {
+func (e FormEncoder) EncodeShaped(v interface{}, shape encoding.Shape) error {
+	return htmlEncode(e.pathPrefix, e.config.templatesFS, e.w, editOn, v, shape)
+}
+
+func htmlEncode(pathPrefix string, templatesFS fs.FS, w io.Writer, edit editMode, v interface{}, shape encoding.Shape) error {
 	err, _ := v.(error)
 	if err != nil {
 		// TODO: handle error better
@@ -107,21 +112,30 @@ func htmlEncode(pathPrefix string, templatesFS fs.FS, w io.Writer, edit editMode
 		for i := 0; i < s.Len(); i++ {
 			s := s.Index(i)
 
-			ent := expandFields(s)
+			ent := expandFields(s, shape, i)
 			if entityName == "" {
 				entityName = ent.Name
 			}
 			entities = append(entities, ent)
+		}
 
-			pd = pageData{
-				PathPrefix: pathPrefix,
-				EntityName: entityName,
+		data := pageData{
+			PathPrefix: pathPrefix,
+			EntityName: entityName,
 
-				Entities: entities,
-			}
+			Entities: entities,
+		}
+
+		rw, ok := w.(http.ResponseWriter)
+		if ok {
+			// the pager uses the same pagination links as the API clients
+			data.Links = parseLinkHeader(rw.Header().Get("Link"))
+			data.TotalCount = rw.Header().Get("X-Total-Count")
 		}
+
+		pd = data
 	} else {
-		res := expandFields(s)
+		res := expandFields(s, shape, 0)
 		if entityName == "" {
 			entityName = res.Name
 		}
@@ -215,6 +229,9 @@ type field struct {
 	Value any
 	Type  string
 	IsID  bool
+
+	// ReadOnly fields are set by the server, they are left out of the forms.
+	ReadOnly bool
 }
 
 type entity struct {
@@ -228,9 +245,43 @@ type pageData struct {
 	EntityName string
 
 	Entities []entity
+
+	// Links are the pagination links of the list, by relation type: "first", "prev", "next", "last".
+	Links map[string]string
+	// TotalCount is the total number of entities, if known.
+	TotalCount string
 }
 
-func expandFields(s reflect.Value) entity {
+// parseLinkHeader parses the RFC 8288 Link header sent by rip, e.g.:
+//
+//	</users/?page=1>; rel="first", </users/?page=3>; rel="next"
+func parseLinkHeader(header string) map[string]string {
+	links := map[string]string{}
+	for _, l := range strings.Split(header, ",") {
+		target, params, ok := strings.Cut(l, ";")
+		if !ok {
+			continue
+		}
+
+		target = strings.TrimSpace(target)
+		target = strings.TrimPrefix(target, "<")
+		target = strings.TrimSuffix(target, ">")
+
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(param, "=")
+			if strings.TrimSpace(name) != "rel" {
+				continue
+			}
+
+			links[strings.Trim(strings.TrimSpace(value), `"`)] = target
+		}
+	}
+
+	return links
+}
+
+// expandFields lists the fields of the index-th entity s, shaped by shape.
+func expandFields(s reflect.Value, shape encoding.Shape, index int) entity {
 	t := s.Type()
 	_, name, _ := strings.Cut(t.String(), ".")
 	if s.Kind() == reflect.Pointer {
@@ -244,11 +295,17 @@ func expandFields(s reflect.Value) entity {
 
 	switch s.Kind() {
 	case reflect.String:
-		ent.Fields = append(ent.Fields, field{"value", s.String(), "string", false})
+		ent.Fields = append(ent.Fields, field{Key: "value", Value: s.String(), Type: "string"})
 	case reflect.Struct:
 		for i := 0; i < s.NumField(); i++ {
 			f := s.Field(i)
 			fName := t.Field(i).Name
+			if !t.Field(i).IsExported() || f.Type() == reflect.TypeOf(xml.Name{}) {
+				continue
+			}
+			if shape.Omit != nil && shape.Omit(t.Field(i)) || shape.Keep != nil && !shape.Keep(t.Field(i)) {
+				continue
+			}
 			fVal := f.Interface()
 
 			fTypeStr := ""
@@ -266,7 +323,27 @@ func expandFields(s reflect.Value) entity {
 				ent.ID = fVal
 				isID = true
 			}
-			ent.Fields = append(ent.Fields, field{fName, fVal, fTypeStr, isID})
+			ent.Fields = append(ent.Fields, field{
+				Key:      fName,
+				Value:    fVal,
+				Type:     fTypeStr,
+				IsID:     isID,
+				ReadOnly: ripreflect.IsReadOnly(t.Field(i)),
+			})
+		}
+
+		if shape.Extra == nil {
+			break
+		}
+
+		for _, extra := range shape.Extra(index) {
+			// an entity included with ?include=
+			ent.Fields = append(ent.Fields, field{
+				Key:      extra.Name,
+				Value:    expandFields(reflect.ValueOf(extra.Value), encoding.Shape{Omit: shape.Omit}, 0),
+				Type:     "entity",
+				ReadOnly: true,
+			})
 		}
 	default:
 		panic("reflect type not handled, yet: " + s.Kind().String())
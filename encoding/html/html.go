@@ -107,5 +107,9 @@ func NewEncoder(pathPrefix string, opts ...Option) func(w io.Writer) *Encoder {
 }
 
 func (e Encoder) Encode(v interface{}) error {
-	return htmlEncode(e.pathPrefix, e.config.templatesFS, e.w, editOff, v)
+	return htmlEncode(e.pathPrefix, e.config.templatesFS, e.w, editOff, v, encoding.Shape{})
+}
+
+func (e Encoder) EncodeShaped(v interface{}, shape encoding.Shape) error {
+	return htmlEncode(e.pathPrefix, e.config.templatesFS, e.w, editOff, v, shape)
 }
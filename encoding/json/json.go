@@ -6,7 +6,7 @@ import (
 	"github.com/dolanor/rip/encoding/codecwrap"
 )
 
-var Codec = codecwrap.Wrap(json.NewEncoder, json.NewDecoder, MimeTypes...)
+var Codec = codecwrap.WithStrictDecoder(codecwrap.Wrap(NewEncoder, json.NewDecoder, MimeTypes...), NewStrictDecoder)
 
 var MimeTypes = []string{
 	"application/json",
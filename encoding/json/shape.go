@@ -0,0 +1,224 @@
+package json
+
+import (
+	"bytes"
+	goencoding "encoding"
+	"encoding/json"
+	"io"
+	"reflect"
+
+	"github.com/dolanor/rip/encoding"
+)
+
+// Encoder is a JSON encoder that can shape the encoded values, see [encoding.ShapedEncoder].
+type Encoder struct {
+	enc *json.Encoder
+}
+
+// NewEncoder creates an [Encoder] writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{enc: json.NewEncoder(w)}
+}
+
+func (e *Encoder) Encode(v any) error {
+	return e.enc.Encode(v)
+}
+
+func (e *Encoder) EncodeShaped(v any, s encoding.Shape) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	data := &node{raw: b}
+	err = encoding.ShapeData(data, v, s, fieldRules)
+	if err != nil {
+		return err
+	}
+
+	return e.enc.Encode(data)
+}
+
+var (
+	marshalerType     = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[goencoding.TextMarshaler]()
+)
+
+func customEncoding(t reflect.Type) bool {
+	return t.Implements(marshalerType) || t.Implements(textMarshalerType)
+}
+
+// node is a JSON value, whose objects and arrays are parsed when they are shaped.
+type node struct {
+	raw json.RawMessage
+
+	parsed bool
+
+	// isObject tells if the value is an object, with members.
+	isObject bool
+	members  []member
+
+	// isArray tells if the value is an array, with items.
+	isArray bool
+	items   []*node
+}
+
+// member is a member of a JSON object, in the order of the object.
+type member struct {
+	name  string
+	value *node
+}
+
+// parse parses the members or the items of the value, once.
+func (n *node) parse() {
+	if n.parsed {
+		return
+	}
+	n.parsed = true
+
+	dec := json.NewDecoder(bytes.NewReader(n.raw))
+	tok, err := dec.Token()
+	if err != nil {
+		return
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		var members []member
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return
+			}
+
+			var raw json.RawMessage
+			err = dec.Decode(&raw)
+			if err != nil {
+				return
+			}
+
+			name, _ := key.(string)
+			members = append(members, member{name: name, value: &node{raw: raw}})
+		}
+
+		n.isObject, n.members = true, members
+	case json.Delim('['):
+		var items []*node
+		for dec.More() {
+			var raw json.RawMessage
+			err = dec.Decode(&raw)
+			if err != nil {
+				return
+			}
+
+			items = append(items, &node{raw: raw})
+		}
+
+		n.isArray, n.items = true, items
+	}
+}
+
+func (n *node) Members(name string) []encoding.Node {
+	n.parse()
+
+	var nodes []encoding.Node
+	for _, m := range n.members {
+		if m.name == name {
+			nodes = append(nodes, m.value)
+		}
+	}
+
+	return nodes
+}
+
+func (n *node) Remove(name string) {
+	n.parse()
+
+	members := n.members[:0]
+	for _, m := range n.members {
+		if m.name != name {
+			members = append(members, m)
+		}
+	}
+	n.members = members
+}
+
+func (n *node) Add(name string, v any) (encoding.Node, error) {
+	n.parse()
+	if !n.isObject {
+		return nil, nil
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	added := &node{raw: b}
+	n.members = append(n.members, member{name: name, value: added})
+	return added, nil
+}
+
+func (n *node) Items() ([]encoding.Node, bool) {
+	n.parse()
+	if !n.isArray {
+		return nil, false
+	}
+
+	items := make([]encoding.Node, len(n.items))
+	for i, item := range n.items {
+		items[i] = item
+	}
+
+	return items, true
+}
+
+func (n *node) MarshalJSON() ([]byte, error) {
+	switch {
+	case n.isObject:
+		var b bytes.Buffer
+		b.WriteByte('{')
+		for i, m := range n.members {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+
+			name, err := json.Marshal(m.name)
+			if err != nil {
+				return nil, err
+			}
+
+			value, err := m.value.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+
+			b.Write(name)
+			b.WriteByte(':')
+			b.Write(value)
+		}
+		b.WriteByte('}')
+
+		return b.Bytes(), nil
+	case n.isArray:
+		var b bytes.Buffer
+		b.WriteByte('[')
+		for i, item := range n.items {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+
+			value, err := item.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+
+			b.Write(value)
+		}
+		b.WriteByte(']')
+
+		return b.Bytes(), nil
+	default:
+		return n.raw, nil
+	}
+}
@@ -0,0 +1,80 @@
+package json
+
+import (
+	"bytes"
+	goencoding "encoding"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/dolanor/rip/encoding"
+)
+
+// StrictDecoder decodes a JSON value, and rejects the unknown fields and the data after the value.
+type StrictDecoder struct {
+	r io.Reader
+}
+
+// NewStrictDecoder creates a [StrictDecoder] reading from r.
+func NewStrictDecoder(r io.Reader) *StrictDecoder {
+	return &StrictDecoder{r: r}
+}
+
+func (d *StrictDecoder) Decode(v any) error {
+	b, err := io.ReadAll(d.r)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	err = dec.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = dec.Token()
+	if err != io.EOF {
+		return encoding.ErrTrailingData
+	}
+
+	// the data is decoded again without type, to find the fields missing in v
+	var data any
+	err = json.Unmarshal(b, &data)
+	if err != nil {
+		return err
+	}
+
+	return encoding.CheckFields(data, reflect.TypeOf(v), fieldRules)
+}
+
+var (
+	unmarshalerType     = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[goencoding.TextUnmarshaler]()
+)
+
+// fieldRules follow the rules of encoding/json to encode and decode the struct fields.
+var fieldRules = encoding.FieldRules{
+	Field: func(f reflect.StructField) (string, reflect.Type, bool) {
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			return "", nil, false
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+
+		return name, f.Type, true
+	},
+	Inline: func(f reflect.StructField) bool {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		return f.Anonymous && name == ""
+	},
+	Custom: func(t reflect.Type) bool {
+		return t.Implements(unmarshalerType) || t.Implements(textUnmarshalerType)
+	},
+	CustomEncoding: customEncoding,
+	FoldCase:       true,
+}
@@ -0,0 +1,219 @@
+package encoding
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MediaRange is a media range of an Accept header, or the media type of a Content-Type header,
+// see RFC 9110 section 12.5.1: type/subtype;param=value;q=0.5
+type MediaRange struct {
+	// Type is the type of the media range, * for any type.
+	Type string
+	// Subtype is the subtype of the media range, * for any subtype.
+	Subtype string
+	// Params are the parameters of the media range, without the quality, e.g. charset.
+	Params map[string]string
+	// Quality is the q parameter, from 0 (not acceptable) to 1 (the default).
+	Quality float64
+}
+
+// MediaType returns the type/subtype of the media range, without parameters.
+func (m MediaRange) MediaType() string {
+	return m.Type + "/" + m.Subtype
+}
+
+// Matches tells if the media type, without parameters, is in the media range.
+func (m MediaRange) Matches(mediaType string) bool {
+	typ, subtype, _ := strings.Cut(strings.ToLower(mediaType), "/")
+	return (m.Type == "*" || m.Type == typ) &&
+		(m.Subtype == "*" || m.Subtype == subtype)
+}
+
+// specificity orders the media ranges matching a media type: the most specific one gives its quality.
+func (m MediaRange) specificity() int {
+	switch {
+	case m.Type == "*":
+		return 0
+	case m.Subtype == "*":
+		return 1
+	default:
+		return 2 + len(m.Params)
+	}
+}
+
+// QualityError is the error of a media range with a bad quality parameter.
+type QualityError struct {
+	MediaRange string
+	Err        error
+}
+
+func (e QualityError) Error() string {
+	return fmt.Sprintf("bad quality of media range %q: %v", e.MediaRange, e.Err)
+}
+
+func (e QualityError) Unwrap() error {
+	return e.Err
+}
+
+// ParseMediaRanges parses the comma separated media ranges of the header values, in their order.
+// The malformed media ranges are ignored, but a bad quality returns a [QualityError].
+func ParseMediaRanges(values ...string) ([]MediaRange, error) {
+	var ranges []MediaRange
+	for _, v := range values {
+		for _, s := range splitQuoted(v, ',') {
+			m, ok, err := parseMediaRange(s)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				ranges = append(ranges, m)
+			}
+		}
+	}
+
+	return ranges, nil
+}
+
+// ParseMediaType parses the media type of a Content-Type header value,
+// e.g. application/json; charset=utf-8. ok is false if it is malformed.
+func ParseMediaType(value string) (m MediaRange, ok bool) {
+	m, ok, err := parseMediaRange(value)
+	if err != nil || m.Type == "*" || m.Subtype == "*" {
+		return MediaRange{}, false
+	}
+
+	return m, ok
+}
+
+func parseMediaRange(s string) (m MediaRange, ok bool, err error) {
+	parts := splitQuoted(s, ';')
+	typ, subtype, found := strings.Cut(strings.ToLower(strings.TrimSpace(parts[0])), "/")
+	typ, subtype = strings.TrimSpace(typ), strings.TrimSpace(subtype)
+	if !found || typ == "" || subtype == "" || (typ == "*" && subtype != "*") {
+		return MediaRange{}, false, nil
+	}
+
+	m = MediaRange{
+		Type:    typ,
+		Subtype: subtype,
+		Quality: 1,
+	}
+
+	for _, p := range parts[1:] {
+		name, value, found := strings.Cut(p, "=")
+		if !found {
+			// not a parameter, ignored
+			continue
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+
+		if name == "q" {
+			q, err := strconv.ParseFloat(value, 64)
+			if err == nil && (q < 0 || q > 1) {
+				err = fmt.Errorf("%v is not between 0 and 1", q)
+			}
+			if err != nil {
+				return MediaRange{}, false, QualityError{MediaRange: strings.TrimSpace(s), Err: err}
+			}
+
+			m.Quality = q
+			// the parameters after the quality are extensions of the Accept header
+			break
+		}
+
+		if m.Params == nil {
+			m.Params = map[string]string{}
+		}
+		m.Params[name] = strings.Trim(value, `"`)
+	}
+
+	return m, true, nil
+}
+
+// splitQuoted splits s around the separators outside of the quoted strings, and trims the spaces.
+func splitQuoted(s string, sep rune) []string {
+	var parts []string
+	var quoted, escaped bool
+	start := 0
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+// Negotiate chooses the media type of offers, ordered by the server preference, which best
+// matches the media ranges of the client, with the rules of RFC 9110 section 12.5.1:
+// the quality of an offer is the one of the most specific media range matching it, and the
+// offers with a 0 quality are not acceptable.
+// Between offers with the same quality, it chooses the one matched by the most specific media
+// range, then by the first one in the client order, then the first one in the server order.
+// ok is false if no offer is acceptable.
+func Negotiate(ranges []MediaRange, offers []string) (best string, ok bool) {
+	var bestQuality float64
+	var bestSpecificity, bestIndex int
+	for _, offer := range offers {
+		quality, specificity, index, matched := offerQuality(ranges, offer)
+		if !matched || quality <= 0 {
+			continue
+		}
+
+		better := !ok ||
+			quality > bestQuality ||
+			(quality == bestQuality && specificity > bestSpecificity) ||
+			(quality == bestQuality && specificity == bestSpecificity && index < bestIndex)
+		if better {
+			best, ok = offer, true
+			bestQuality, bestSpecificity, bestIndex = quality, specificity, index
+		}
+	}
+
+	return best, ok
+}
+
+// offerQuality finds the most specific media range of ranges matching the offer, and returns its
+// quality, its specificity and its index.
+func offerQuality(ranges []MediaRange, offer string) (quality float64, specificity, index int, matched bool) {
+	for i, m := range ranges {
+		if !m.Matches(offer) {
+			continue
+		}
+
+		s := m.specificity()
+		if !matched || s > specificity {
+			quality, specificity, index, matched = m.Quality, s, i, true
+		}
+	}
+
+	return quality, specificity, index, matched
+}
+
+// codec finds the codec of the media type of the Content-Type header value, ignoring its parameters.
+func (c Codecs) codec(contentTypeHeader string) (Codec, bool) {
+	codec, ok := c.Codecs[contentTypeHeader]
+	if ok {
+		return codec, true
+	}
+
+	m, ok := ParseMediaType(contentTypeHeader)
+	if !ok {
+		return Codec{}, false
+	}
+
+	codec, ok = c.Codecs[m.MediaType()]
+	return codec, ok
+}
@@ -5,7 +5,7 @@ import (
 	"github.com/vmihailenco/msgpack/v5"
 )
 
-var Codec = codecwrap.Wrap(msgpack.NewEncoder, msgpack.NewDecoder, MimeTypes...)
+var Codec = codecwrap.Wrap(NewEncoder, msgpack.NewDecoder, MimeTypes...)
 
 var MimeTypes = []string{
 	"application/msgpack",
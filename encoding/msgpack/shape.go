@@ -0,0 +1,253 @@
+package msgpack
+
+import (
+	"bytes"
+	goencoding "encoding"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/vmihailenco/msgpack/v5/msgpcode"
+
+	"github.com/dolanor/rip/encoding"
+)
+
+// Encoder is a MessagePack encoder that can shape the encoded values, see [encoding.ShapedEncoder].
+type Encoder struct {
+	enc *msgpack.Encoder
+}
+
+// NewEncoder creates an [Encoder] writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{enc: msgpack.NewEncoder(w)}
+}
+
+func (e *Encoder) Encode(v any) error {
+	return e.enc.Encode(v)
+}
+
+func (e *Encoder) EncodeShaped(v any, s encoding.Shape) error {
+	b, err := msgpack.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	data := &node{raw: b}
+	err = encoding.ShapeData(data, v, s, fieldRules)
+	if err != nil {
+		return err
+	}
+
+	return e.enc.Encode(data)
+}
+
+var (
+	customEncoderType   = reflect.TypeFor[msgpack.CustomEncoder]()
+	marshalerType       = reflect.TypeFor[msgpack.Marshaler]()
+	binaryMarshalerType = reflect.TypeFor[goencoding.BinaryMarshaler]()
+	textMarshalerType   = reflect.TypeFor[goencoding.TextMarshaler]()
+)
+
+// fieldRules follow the rules of github.com/vmihailenco/msgpack/v5 to encode the struct fields.
+var fieldRules = encoding.FieldRules{
+	Field: func(f reflect.StructField) (string, reflect.Type, bool) {
+		name, _, _ := strings.Cut(f.Tag.Get("msgpack"), ",")
+		if name == "-" {
+			return "", nil, false
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		return name, f.Type, true
+	},
+	Inline: func(f reflect.StructField) bool {
+		_, options, _ := strings.Cut(f.Tag.Get("msgpack"), ",")
+		return f.Anonymous && !slices.Contains(strings.Split(options, ","), "noinline")
+	},
+	CustomEncoding: func(t reflect.Type) bool {
+		return t.Implements(customEncoderType) || t.Implements(marshalerType) ||
+			t.Implements(binaryMarshalerType) || t.Implements(textMarshalerType)
+	},
+}
+
+// node is a MessagePack value, whose maps and arrays are parsed when they are shaped.
+type node struct {
+	raw msgpack.RawMessage
+
+	parsed bool
+
+	// isMap tells if the value is a map, with members.
+	isMap   bool
+	members []member
+
+	// isArray tells if the value is an array, with items.
+	isArray bool
+	items   []*node
+}
+
+// member is a member of a MessagePack map, in the order of the map.
+type member struct {
+	key msgpack.RawMessage
+
+	// name is the key, if it is a string.
+	name  string
+	value *node
+}
+
+// parse parses the members or the items of the value, once.
+func (n *node) parse() {
+	if n.parsed {
+		return
+	}
+	n.parsed = true
+
+	dec := msgpack.NewDecoder(bytes.NewReader(n.raw))
+	c, err := dec.PeekCode()
+	if err != nil {
+		return
+	}
+
+	switch {
+	case msgpcode.IsFixedMap(c) || c == msgpcode.Map16 || c == msgpcode.Map32:
+		l, err := dec.DecodeMapLen()
+		if err != nil {
+			return
+		}
+
+		members := make([]member, 0, l)
+		for i := 0; i < l; i++ {
+			key, err := dec.DecodeRaw()
+			if err != nil {
+				return
+			}
+
+			value, err := dec.DecodeRaw()
+			if err != nil {
+				return
+			}
+
+			var name string
+			_ = msgpack.Unmarshal(key, &name)
+			members = append(members, member{key: key, name: name, value: &node{raw: value}})
+		}
+
+		n.isMap, n.members = true, members
+	case msgpcode.IsFixedArray(c) || c == msgpcode.Array16 || c == msgpcode.Array32:
+		l, err := dec.DecodeArrayLen()
+		if err != nil {
+			return
+		}
+
+		items := make([]*node, 0, l)
+		for i := 0; i < l; i++ {
+			value, err := dec.DecodeRaw()
+			if err != nil {
+				return
+			}
+
+			items = append(items, &node{raw: value})
+		}
+
+		n.isArray, n.items = true, items
+	}
+}
+
+func (n *node) Members(name string) []encoding.Node {
+	n.parse()
+
+	var nodes []encoding.Node
+	for _, m := range n.members {
+		if m.name == name {
+			nodes = append(nodes, m.value)
+		}
+	}
+
+	return nodes
+}
+
+func (n *node) Remove(name string) {
+	n.parse()
+
+	n.members = slices.DeleteFunc(n.members, func(m member) bool {
+		return m.name == name
+	})
+}
+
+func (n *node) Add(name string, v any) (encoding.Node, error) {
+	n.parse()
+	if !n.isMap {
+		return nil, nil
+	}
+
+	key, err := msgpack.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := msgpack.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	added := &node{raw: value}
+	n.members = append(n.members, member{key: key, name: name, value: added})
+	return added, nil
+}
+
+func (n *node) Items() ([]encoding.Node, bool) {
+	n.parse()
+	if !n.isArray {
+		return nil, false
+	}
+
+	items := make([]encoding.Node, len(n.items))
+	for i, item := range n.items {
+		items[i] = item
+	}
+
+	return items, true
+}
+
+func (n *node) EncodeMsgpack(enc *msgpack.Encoder) error {
+	switch {
+	case n.isMap:
+		err := enc.EncodeMapLen(len(n.members))
+		if err != nil {
+			return err
+		}
+
+		for _, m := range n.members {
+			err = enc.Encode(m.key)
+			if err != nil {
+				return err
+			}
+
+			err = m.value.EncodeMsgpack(enc)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case n.isArray:
+		err := enc.EncodeArrayLen(len(n.items))
+		if err != nil {
+			return err
+		}
+
+		for _, item := range n.items {
+			err = item.EncodeMsgpack(enc)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	default:
+		return enc.Encode(n.raw)
+	}
+}
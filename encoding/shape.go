@@ -0,0 +1,294 @@
+package encoding
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// Shape changes the data of an encoded value, without changing its type: a [ShapedEncoder]
+// encodes the value as usual, with its custom marshalers, then removes or adds fields in the data.
+type Shape struct {
+	// Omit tells if the struct field f is left out of the data, in the value and in all its
+	// nested values.
+	Omit func(f reflect.StructField) bool
+
+	// Keep tells if the struct field f of an entity is kept in the data. All the fields are
+	// kept if it is nil.
+	// The entities are the encoded value, or the elements of the encoded list.
+	Keep func(f reflect.StructField) bool
+
+	// Extra returns the fields added to the data of the i-th entity.
+	Extra func(i int) []Field
+}
+
+// IsZero tells if the shape doesn't change the data.
+func (s Shape) IsZero() bool {
+	return s.Omit == nil && s.Keep == nil && s.Extra == nil
+}
+
+// Field is a field added to the data of an entity, see [Shape].
+type Field struct {
+	Name  string
+	Value any
+}
+
+// ShapedEncoder is an [Encoder] that can change the data of the encoded values with a [Shape].
+type ShapedEncoder interface {
+	Encoder
+
+	EncodeShaped(v any, s Shape) error
+}
+
+// Node is the data of an encoded value, changed by [ShapeData].
+// The methods of the objects do nothing on the other nodes.
+type Node interface {
+	// Members returns the data of the member name of an object.
+	// The repeated elements of a list are several members, in the codecs without list syntax (e.g. XML).
+	Members(name string) []Node
+
+	// Remove removes the member name of an object.
+	Remove(name string)
+
+	// Add adds the member name with the data of v to an object, and returns its data.
+	Add(name string, v any) (Node, error)
+
+	// Items returns the data of the elements of a list. ok is false if the node is not a list.
+	Items() (items []Node, ok bool)
+}
+
+// ShapeData changes data, the encoded data of v, with the shape s.
+// rules are the rules of the codec to name the struct fields in the data.
+// The data of the nested values encoding themselves is left as is.
+func ShapeData(data Node, v any, s Shape, rules FieldRules) error {
+	sh := shaper{shape: s, rules: rules}
+
+	value := indirect(reflect.ValueOf(v))
+	if isList(value) {
+		items, ok := data.Items()
+		if !ok {
+			items = []Node{data}
+		}
+		if len(items) != value.Len() {
+			return nil
+		}
+
+		for i, item := range items {
+			err := sh.entity(item, value.Index(i), i)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return sh.entity(data, value, 0)
+}
+
+type shaper struct {
+	shape Shape
+	rules FieldRules
+}
+
+// entity shapes the data of the i-th entity.
+func (sh shaper) entity(data Node, v reflect.Value, i int) error {
+	v = indirect(v)
+	if v.Kind() == reflect.Struct && sh.shape.Keep != nil {
+		for j := 0; j < v.NumField(); j++ {
+			f := v.Type().Field(j)
+			if !sh.shape.Keep(f) {
+				sh.remove(data, f)
+			}
+		}
+	}
+
+	// the entity is shaped even if it encodes itself
+	sh.value(data, v, true)
+
+	if sh.shape.Extra == nil {
+		return nil
+	}
+
+	for _, f := range sh.shape.Extra(i) {
+		added, err := data.Add(f.Name, f.Value)
+		if err != nil {
+			return err
+		}
+
+		if added != nil {
+			sh.value(added, reflect.ValueOf(f.Value), true)
+		}
+	}
+
+	return nil
+}
+
+// value removes the omitted fields of v and of its nested values from data.
+func (sh shaper) value(data Node, v reflect.Value, isEntity bool) {
+	v = indirect(v)
+	if !v.IsValid() || sh.shape.Omit == nil {
+		return
+	}
+
+	if !isEntity && sh.custom(v.Type()) {
+		return
+	}
+
+	switch {
+	case v.Kind() == reflect.Struct:
+		sh.fields(data, v)
+	case isList(v):
+		items, ok := data.Items()
+		if !ok {
+			// a list with a single element, in the codecs without list syntax (e.g. XML)
+			if v.Len() == 1 {
+				sh.value(data, v.Index(0), false)
+			}
+			return
+		}
+		if len(items) != v.Len() {
+			return
+		}
+
+		for i, item := range items {
+			sh.value(item, v.Index(i), false)
+		}
+	case v.Kind() == reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			name, ok := mapKey(iter.Key())
+			if !ok {
+				continue
+			}
+
+			sh.members(data.Members(name), iter.Value())
+		}
+	}
+}
+
+// fields removes the omitted fields of the struct v from data.
+func (sh shaper) fields(data Node, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if sh.shape.Omit(f) {
+			sh.remove(data, f)
+			continue
+		}
+
+		if sh.rules.Rest != nil && sh.rules.Rest(f) {
+			continue
+		}
+
+		if sh.inline(f) {
+			fv := indirect(v.Field(i))
+			if fv.IsValid() {
+				sh.fields(data, fv)
+			}
+			continue
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		name, _, ok := sh.rules.Field(f)
+		if ok {
+			sh.members(data.Members(name), v.Field(i))
+		}
+	}
+}
+
+// members removes the omitted fields from the data of the members holding v.
+func (sh shaper) members(nodes []Node, v reflect.Value) {
+	if len(nodes) > 1 {
+		// the repeated elements of a list, in the codecs without list syntax (e.g. XML)
+		v = indirect(v)
+		if !isList(v) || v.Len() != len(nodes) {
+			return
+		}
+
+		for i, n := range nodes {
+			sh.value(n, v.Index(i), false)
+		}
+		return
+	}
+
+	for _, n := range nodes {
+		sh.value(n, v, false)
+	}
+}
+
+// remove removes the data of the struct field f, or of its fields if they are inline.
+func (sh shaper) remove(data Node, f reflect.StructField) {
+	if sh.inline(f) {
+		t := f.Type
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		for i := 0; i < t.NumField(); i++ {
+			sh.remove(data, t.Field(i))
+		}
+		return
+	}
+
+	if !f.IsExported() {
+		return
+	}
+
+	name, _, ok := sh.rules.Field(f)
+	if ok {
+		data.Remove(name)
+	}
+}
+
+// inline tells if the fields of the struct field f are in the data of its parent struct.
+func (sh shaper) inline(f reflect.StructField) bool {
+	if sh.rules.Inline == nil || !sh.rules.Inline(f) {
+		return false
+	}
+
+	t := f.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && !sh.custom(t)
+}
+
+// custom tells if the values of type t encode themselves.
+func (sh shaper) custom(t reflect.Type) bool {
+	if sh.rules.CustomEncoding == nil {
+		return false
+	}
+
+	return sh.rules.CustomEncoding(t) || sh.rules.CustomEncoding(reflect.PointerTo(t))
+}
+
+// indirect returns the value pointed by v, through its pointers and interfaces.
+// It is invalid if one of them is nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// isList tells if v is a list in the data, the byte slices are not.
+func isList(v reflect.Value) bool {
+	return (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8
+}
+
+// mapKey returns the name of the member of a map key in the data.
+func mapKey(k reflect.Value) (string, bool) {
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), true
+	default:
+		return "", false
+	}
+}
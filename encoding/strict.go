@@ -0,0 +1,192 @@
+package encoding
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrTrailingData is the error of a strict decoder for data after the decoded value.
+var ErrTrailingData = errors.New("trailing data after the decoded value")
+
+// UnknownFieldError is the error of a strict decoder for a field of the data that doesn't
+// exist in the decoded value.
+type UnknownFieldError struct {
+	// Path is the path of the unknown field in the data, e.g. ["address", "zip_code"].
+	Path []string
+}
+
+func (e UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", strings.Join(e.Path, "/"))
+}
+
+// ErrorSourcePointer returns the JSON pointer to the unknown field, e.g. /address/zip_code.
+func (e UnknownFieldError) ErrorSourcePointer() string {
+	return "/" + strings.Join(e.Path, "/")
+}
+
+// FieldRules describe how the fields of a struct are named in the data of a codec,
+// to check the fields of the data with [CheckFields], or to shape it with [ShapeData].
+type FieldRules struct {
+	// Field returns the name of the struct field f in the data, and the type of the values
+	// to check in it. ok is false if the field is not in the data.
+	Field func(f reflect.StructField) (name string, t reflect.Type, ok bool)
+
+	// Inline tells if the fields of the struct field f are in its parent struct.
+	Inline func(f reflect.StructField) bool
+
+	// Rest tells if the struct field f holds all the unknown fields of the data.
+	Rest func(f reflect.StructField) bool
+
+	// Custom tells if the values of type t decode themselves, so their data is not checked.
+	Custom func(t reflect.Type) bool
+
+	// CustomEncoding tells if the values of type t encode themselves, so their data is not shaped.
+	CustomEncoding func(t reflect.Type) bool
+
+	// FoldCase matches the names of the fields case-insensitively.
+	FoldCase bool
+}
+
+// CheckFields checks that the fields of data exist in the type t, recursively.
+// data is the generic representation of the decoded data: map[string]any for the objects,
+// []any for the lists.
+// It returns an [UnknownFieldError] with the path of the first unknown field.
+func CheckFields(data any, t reflect.Type, rules FieldRules) error {
+	return checkFields(data, t, nil, rules)
+}
+
+func checkFields(data any, t reflect.Type, path []string, rules FieldRules) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if rules.Custom != nil && (rules.Custom(t) || rules.Custom(reflect.PointerTo(t))) {
+		return nil
+	}
+
+	switch d := data.(type) {
+	case map[string]any:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			// a list with a single element, in the codecs without list syntax (e.g. XML)
+			t = t.Elem()
+			for t.Kind() == reflect.Pointer {
+				t = t.Elem()
+			}
+		}
+
+		var fields map[string]reflect.Type
+		switch t.Kind() {
+		case reflect.Struct:
+			var rest bool
+			fields, rest = structFields(t, rules)
+			if rest {
+				return nil
+			}
+		case reflect.Map:
+		default:
+			return nil
+		}
+
+		keys := make([]string, 0, len(d))
+		for k := range d {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+
+		for _, k := range keys {
+			fieldPath := append(slices.Clone(path), k)
+
+			ft := t
+			if t.Kind() == reflect.Map {
+				ft = t.Elem()
+			} else {
+				var ok bool
+				ft, ok = lookupField(fields, k, rules.FoldCase)
+				if !ok {
+					return UnknownFieldError{Path: fieldPath}
+				}
+			}
+
+			err := checkFields(d[k], ft, fieldPath, rules)
+			if err != nil {
+				return err
+			}
+		}
+	case []any:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return nil
+		}
+
+		for i, v := range d {
+			err := checkFields(v, t.Elem(), append(slices.Clone(path), strconv.Itoa(i)), rules)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// structFields returns the types of the fields of the struct type t, by their name in the data.
+// rest is true if t holds all the unknown fields.
+func structFields(t reflect.Type, rules FieldRules) (fields map[string]reflect.Type, rest bool) {
+	fields = map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if rules.Rest != nil && rules.Rest(f) {
+			return nil, true
+		}
+
+		if rules.Inline != nil && rules.Inline(f) {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			if ft.Kind() == reflect.Struct {
+				embedded, rest := structFields(ft, rules)
+				if rest {
+					return nil, true
+				}
+
+				for name, et := range embedded {
+					if _, ok := fields[name]; !ok {
+						fields[name] = et
+					}
+				}
+				continue
+			}
+		}
+
+		if !f.IsExported() {
+			continue
+		}
+
+		name, ft, ok := rules.Field(f)
+		if ok {
+			fields[name] = ft
+		}
+	}
+
+	return fields, false
+}
+
+func lookupField(fields map[string]reflect.Type, name string, foldCase bool) (reflect.Type, bool) {
+	t, ok := fields[name]
+	if ok || !foldCase {
+		return t, ok
+	}
+
+	for fieldName, t := range fields {
+		if strings.EqualFold(fieldName, name) {
+			return t, true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,269 @@
+package xml
+
+import (
+	"bytes"
+	goencoding "encoding"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/dolanor/rip/encoding"
+)
+
+// Encoder is an XML encoder that can shape the encoded values, see [encoding.ShapedEncoder].
+type Encoder struct {
+	w   io.Writer
+	enc *xml.Encoder
+}
+
+// NewEncoder creates an [Encoder] writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, enc: xml.NewEncoder(w)}
+}
+
+func (e *Encoder) Encode(v any) error {
+	return e.enc.Encode(v)
+}
+
+func (e *Encoder) EncodeShaped(v any, s encoding.Shape) error {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	doc, err := parseElements(b)
+	if err != nil {
+		return err
+	}
+
+	var data encoding.Node = doc
+	value := reflect.Indirect(reflect.ValueOf(v))
+	isList := value.Kind() == reflect.Slice || value.Kind() == reflect.Array
+	if roots := doc.elements(); !isList && len(roots) > 0 {
+		data = roots[0]
+	}
+
+	err = encoding.ShapeData(data, v, s, shapeRules)
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	doc.write(&out)
+	_, err = e.w.Write(out.Bytes())
+	return err
+}
+
+var (
+	marshalerType     = reflect.TypeFor[xml.Marshaler]()
+	textMarshalerType = reflect.TypeFor[goencoding.TextMarshaler]()
+)
+
+// shapeRules follow the rules of encoding/xml to encode the struct fields in child elements
+// and attributes.
+var shapeRules = encoding.FieldRules{
+	Field: func(f reflect.StructField) (string, reflect.Type, bool) {
+		if f.Name == "XMLName" {
+			return "", nil, false
+		}
+
+		tag := f.Tag.Get("xml")
+		if tag == "-" {
+			return "", nil, false
+		}
+
+		name, options := splitTag(tag)
+		for _, o := range []string{"chardata", "cdata", "innerxml", "comment"} {
+			if slices.Contains(options, o) {
+				return "", nil, false
+			}
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		return name, f.Type, true
+	},
+	Inline: fieldRules.Inline,
+	Rest:   fieldRules.Rest,
+	CustomEncoding: func(t reflect.Type) bool {
+		return t.Implements(marshalerType) || t.Implements(textMarshalerType)
+	},
+}
+
+// element is an XML element, or the document with the root elements.
+// Its content are the child *element and the other tokens.
+type element struct {
+	start   xml.StartElement
+	content []any
+}
+
+// parseElements parses the XML document b.
+func parseElements(b []byte) (*element, error) {
+	doc := &element{}
+	stack := []*element{doc}
+
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := dec.RawToken()
+		if errors.Is(err, io.EOF) {
+			return doc, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			e := &element{start: t.Copy()}
+			parent.content = append(parent.content, e)
+			stack = append(stack, e)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			parent.content = append(parent.content, xml.CopyToken(t))
+		}
+	}
+}
+
+// elements returns the child elements of e.
+func (e *element) elements() []*element {
+	var elements []*element
+	for _, c := range e.content {
+		child, ok := c.(*element)
+		if ok {
+			elements = append(elements, child)
+		}
+	}
+
+	return elements
+}
+
+// Members returns the child elements named name, or a>b for the b elements in the a elements.
+func (e *element) Members(name string) []encoding.Node {
+	parent, nestedName, nested := strings.Cut(name, ">")
+	var nodes []encoding.Node
+	for _, child := range e.elements() {
+		if child.start.Name.Local != parent {
+			continue
+		}
+
+		if nested {
+			nodes = append(nodes, child.Members(nestedName)...)
+		} else {
+			nodes = append(nodes, child)
+		}
+	}
+
+	return nodes
+}
+
+// Remove removes the attributes and the child elements named name, or a>b for the b
+// elements in the a elements.
+func (e *element) Remove(name string) {
+	parent, child, nested := strings.Cut(name, ">")
+	if nested {
+		for _, el := range e.elements() {
+			if el.start.Name.Local == parent {
+				el.Remove(child)
+			}
+		}
+		return
+	}
+
+	e.start.Attr = slices.DeleteFunc(e.start.Attr, func(a xml.Attr) bool {
+		return a.Name.Local == name
+	})
+	e.content = slices.DeleteFunc(e.content, func(c any) bool {
+		child, ok := c.(*element)
+		return ok && child.start.Name.Local == name
+	})
+}
+
+func (e *element) Add(name string, v any) (encoding.Node, error) {
+	var b bytes.Buffer
+	err := xml.NewEncoder(&b).EncodeElement(v, xml.StartElement{Name: xml.Name{Local: name}})
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := parseElements(b.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	added := doc.elements()
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	e.content = append(e.content, added[0])
+	return added[0], nil
+}
+
+// Items returns the root elements of the document, the list of the encoded values.
+func (e *element) Items() ([]encoding.Node, bool) {
+	if e.start.Name.Local != "" {
+		return nil, false
+	}
+
+	var items []encoding.Node
+	for _, child := range e.elements() {
+		items = append(items, child)
+	}
+
+	return items, true
+}
+
+// write writes e, as encoded by encoding/xml.
+func (e *element) write(b *bytes.Buffer) {
+	isDocument := e.start.Name.Local == ""
+	if !isDocument {
+		b.WriteString("<" + qualifiedName(e.start.Name))
+		for _, a := range e.start.Attr {
+			b.WriteString(" " + qualifiedName(a.Name) + `="`)
+			xml.EscapeText(b, []byte(a.Value))
+			b.WriteString(`"`)
+		}
+		b.WriteString(">")
+	}
+
+	for _, c := range e.content {
+		switch t := c.(type) {
+		case *element:
+			t.write(b)
+		case xml.CharData:
+			xml.EscapeText(b, t)
+		case xml.Comment:
+			b.WriteString("<!--" + string(t) + "-->")
+		case xml.ProcInst:
+			b.WriteString("<?" + t.Target)
+			if len(t.Inst) > 0 {
+				b.WriteString(" " + string(t.Inst))
+			}
+			b.WriteString("?>")
+		case xml.Directive:
+			b.WriteString("<!" + string(t) + ">")
+		}
+	}
+
+	if !isDocument {
+		b.WriteString("</" + qualifiedName(e.start.Name) + ">")
+	}
+}
+
+// qualifiedName returns the name with its namespace prefix, as read by [xml.Decoder.RawToken].
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+
+	return name.Space + ":" + name.Local
+}
@@ -0,0 +1,186 @@
+package xml
+
+import (
+	"bytes"
+	goencoding "encoding"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/dolanor/rip/encoding"
+)
+
+// StrictDecoder decodes an XML element, and rejects the unknown child elements and the data
+// after the element.
+// The attributes are not checked.
+type StrictDecoder struct {
+	r io.Reader
+}
+
+// NewStrictDecoder creates a [StrictDecoder] reading from r.
+func NewStrictDecoder(r io.Reader) *StrictDecoder {
+	return &StrictDecoder{r: r}
+}
+
+func (d *StrictDecoder) Decode(v any) error {
+	b, err := io.ReadAll(d.r)
+	if err != nil {
+		return err
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	err = dec.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil || !ignorable(tok) {
+			return encoding.ErrTrailingData
+		}
+	}
+
+	// the element is read again without type, to find the child elements missing in v
+	data, err := elementTree(xml.NewDecoder(bytes.NewReader(b)))
+	if err != nil {
+		return err
+	}
+
+	return encoding.CheckFields(data, reflect.TypeOf(v), fieldRules)
+}
+
+// ignorable tells if tok can be after the decoded element.
+func ignorable(tok xml.Token) bool {
+	switch t := tok.(type) {
+	case xml.CharData:
+		return len(bytes.TrimSpace(t)) == 0
+	case xml.Comment, xml.ProcInst:
+		return true
+	default:
+		return false
+	}
+}
+
+// elementTree reads the first element of dec, as a map of its child elements by name.
+// The repeated child elements are in a list.
+func elementTree(dec *xml.Decoder) (map[string]any, error) {
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		_, ok := tok.(xml.StartElement)
+		if ok {
+			return children(dec)
+		}
+	}
+}
+
+// children reads the child elements of the element just started in dec.
+func children(dec *xml.Decoder) (map[string]any, error) {
+	tree := map[string]any{}
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			child, err := children(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			var value any = child
+			if len(child) == 0 {
+				// the content of the element is text
+				value = ""
+			}
+
+			name := t.Name.Local
+			switch prev := tree[name].(type) {
+			case nil:
+				tree[name] = value
+			case []any:
+				tree[name] = append(prev, value)
+			default:
+				tree[name] = []any{prev, value}
+			}
+		case xml.EndElement:
+			return tree, nil
+		}
+	}
+}
+
+var (
+	unmarshalerType     = reflect.TypeFor[xml.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[goencoding.TextUnmarshaler]()
+	anyType             = reflect.TypeFor[any]()
+)
+
+// fieldRules follow the rules of encoding/xml to decode the child elements in the struct fields.
+var fieldRules = encoding.FieldRules{
+	Field: func(f reflect.StructField) (string, reflect.Type, bool) {
+		if f.Name == "XMLName" {
+			return "", nil, false
+		}
+
+		tag := f.Tag.Get("xml")
+		if tag == "-" {
+			return "", nil, false
+		}
+
+		name, options := splitTag(tag)
+		for _, o := range []string{"attr", "chardata", "cdata", "innerxml", "comment"} {
+			if slices.Contains(options, o) {
+				return "", nil, false
+			}
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		parent, _, nested := strings.Cut(name, ">")
+		if nested {
+			// the nested elements (a>b) are not checked
+			return parent, anyType, true
+		}
+
+		return name, f.Type, true
+	},
+	Inline: func(f reflect.StructField) bool {
+		name, _ := splitTag(f.Tag.Get("xml"))
+		return f.Anonymous && name == ""
+	},
+	Rest: func(f reflect.StructField) bool {
+		_, options := splitTag(f.Tag.Get("xml"))
+		return slices.Contains(options, "any")
+	},
+	Custom: func(t reflect.Type) bool {
+		return t.Implements(unmarshalerType) || t.Implements(textUnmarshalerType)
+	},
+}
+
+// splitTag splits an xml struct tag in the element name, without namespace, and its options.
+func splitTag(tag string) (name string, options []string) {
+	name, opts, _ := strings.Cut(tag, ",")
+	if i := strings.LastIndex(name, " "); i >= 0 {
+		name = name[i+1:]
+	}
+
+	if opts != "" {
+		options = strings.Split(opts, ",")
+	}
+
+	return name, options
+}
@@ -6,7 +6,7 @@ import (
 	"github.com/dolanor/rip/encoding/codecwrap"
 )
 
-var Codec = codecwrap.Wrap(xml.NewEncoder, xml.NewDecoder, MimeTypes...)
+var Codec = codecwrap.WithStrictDecoder(codecwrap.Wrap(NewEncoder, xml.NewDecoder, MimeTypes...), NewStrictDecoder)
 
 var MimeTypes = []string{
 	"application/xml",
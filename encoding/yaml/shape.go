@@ -0,0 +1,112 @@
+package yaml
+
+import (
+	goencoding "encoding"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dolanor/rip/encoding"
+)
+
+// Encoder is a YAML encoder that can shape the encoded values, see [encoding.ShapedEncoder].
+type Encoder struct {
+	enc *yaml.Encoder
+}
+
+// NewEncoder creates an [Encoder] writing to w.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{enc: yaml.NewEncoder(w)}
+}
+
+func (e *Encoder) Encode(v any) error {
+	return e.enc.Encode(v)
+}
+
+func (e *Encoder) EncodeShaped(v any, s encoding.Shape) error {
+	var data yaml.Node
+	err := data.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	err = encoding.ShapeData(node{&data}, v, s, fieldRules)
+	if err != nil {
+		return err
+	}
+
+	return e.enc.Encode(&data)
+}
+
+var (
+	marshalerType     = reflect.TypeFor[yaml.Marshaler]()
+	textMarshalerType = reflect.TypeFor[goencoding.TextMarshaler]()
+)
+
+func customEncoding(t reflect.Type) bool {
+	return t.Implements(marshalerType) || t.Implements(textMarshalerType)
+}
+
+// node is a YAML node being shaped.
+type node struct {
+	n *yaml.Node
+}
+
+func (n node) Members(name string) []encoding.Node {
+	if n.n.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var nodes []encoding.Node
+	for i := 0; i+1 < len(n.n.Content); i += 2 {
+		if n.n.Content[i].Value == name {
+			nodes = append(nodes, node{n.n.Content[i+1]})
+		}
+	}
+
+	return nodes
+}
+
+func (n node) Remove(name string) {
+	if n.n.Kind != yaml.MappingNode {
+		return
+	}
+
+	content := n.n.Content[:0]
+	for i := 0; i+1 < len(n.n.Content); i += 2 {
+		if n.n.Content[i].Value != name {
+			content = append(content, n.n.Content[i], n.n.Content[i+1])
+		}
+	}
+	n.n.Content = content
+}
+
+func (n node) Add(name string, v any) (encoding.Node, error) {
+	if n.n.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var value yaml.Node
+	err := value.Encode(v)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name}
+	n.n.Content = append(n.n.Content, key, &value)
+	return node{&value}, nil
+}
+
+func (n node) Items() ([]encoding.Node, bool) {
+	if n.n.Kind != yaml.SequenceNode {
+		return nil, false
+	}
+
+	items := make([]encoding.Node, len(n.n.Content))
+	for i, item := range n.n.Content {
+		items[i] = node{item}
+	}
+
+	return items, true
+}
@@ -0,0 +1,110 @@
+package yaml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/dolanor/rip/encoding"
+)
+
+// StrictDecoder decodes a YAML document, and rejects the unknown fields and the documents
+// after the first one.
+type StrictDecoder struct {
+	r io.Reader
+}
+
+// NewStrictDecoder creates a [StrictDecoder] reading from r.
+func NewStrictDecoder(r io.Reader) *StrictDecoder {
+	return &StrictDecoder{r: r}
+}
+
+func (d *StrictDecoder) Decode(v any) error {
+	b, err := io.ReadAll(d.r)
+	if err != nil {
+		return err
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(b))
+	err = dec.Decode(v)
+	if err != nil {
+		return err
+	}
+
+	var next yaml.Node
+	err = dec.Decode(&next)
+	if !errors.Is(err, io.EOF) {
+		return encoding.ErrTrailingData
+	}
+
+	// the document is decoded again without type, to find the fields missing in v
+	var data any
+	err = yaml.Unmarshal(b, &data)
+	if err != nil {
+		return err
+	}
+
+	return encoding.CheckFields(stringKeys(data), reflect.TypeOf(v), fieldRules)
+}
+
+// stringKeys converts the maps with non string keys of data, so they can be checked.
+func stringKeys(data any) any {
+	switch d := data.(type) {
+	case map[string]any:
+		for k, v := range d {
+			d[k] = stringKeys(v)
+		}
+		return d
+	case map[any]any:
+		m := make(map[string]any, len(d))
+		for k, v := range d {
+			m[fmt.Sprint(k)] = stringKeys(v)
+		}
+		return m
+	case []any:
+		for i, v := range d {
+			d[i] = stringKeys(v)
+		}
+		return d
+	default:
+		return data
+	}
+}
+
+var unmarshalerType = reflect.TypeFor[yaml.Unmarshaler]()
+
+// fieldRules follow the rules of gopkg.in/yaml.v3 to encode and decode the struct fields.
+var fieldRules = encoding.FieldRules{
+	Field: func(f reflect.StructField) (string, reflect.Type, bool) {
+		tag := f.Tag.Get("yaml")
+		if tag == "-" {
+			return "", nil, false
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		return name, f.Type, true
+	},
+	Inline: isInline,
+	Rest: func(f reflect.StructField) bool {
+		return isInline(f) && f.Type.Kind() == reflect.Map
+	},
+	Custom: func(t reflect.Type) bool {
+		return t.Implements(unmarshalerType)
+	},
+	CustomEncoding: customEncoding,
+}
+
+func isInline(f reflect.StructField) bool {
+	_, options, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+	return slices.Contains(strings.Split(options, ","), "inline")
+}
@@ -5,7 +5,7 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-var Codec = codecwrap.Wrap(yaml.NewEncoder, yaml.NewDecoder, MimeTypes...)
+var Codec = codecwrap.WithStrictDecoder(codecwrap.Wrap(NewEncoder, yaml.NewDecoder, MimeTypes...), NewStrictDecoder)
 
 var MimeTypes = []string{
 	"text/vnd.yaml",
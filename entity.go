@@ -25,3 +25,57 @@ type EntityProvider[Ent any] interface {
 }
 
 // end EntityProvider OMIT
+
+// EntityVersioner can be implemented by an [EntityProvider] that keeps track of the
+// version of its entities (e.g. a revision number).
+// The version is used as the entity tag (ETag) of the entity, instead of a hash of
+// the entity data.
+type EntityVersioner[Ent any] interface {
+	// Version returns the version of ent.
+	Version(ent Ent) string
+}
+
+// FilterLister can be implemented by an [EntityProvider] that can list the entities
+// matching a [Filter]. The filter is parsed from the list query parameters, e.g.:
+//
+//	GET /entities/?name=foo&age[gt]=30&status[in]=a,b
+//
+// Without it, the list query parameters are not filters, and they are ignored.
+type FilterLister[Ent any] interface {
+	// ListFiltered lists a group of the entities matching filter.
+	ListFiltered(ctx context.Context, filter Filter, offset, limit int) ([]Ent, error)
+}
+
+// SortLister can be implemented by an [EntityProvider] that can list its entities in
+// the order of a [Sort]. The sort is parsed from the sort list query parameter, e.g.:
+//
+//	GET /entities/?sort=-created,name
+//
+// filter is always empty if the provider doesn't implement [FilterLister] as well.
+type SortLister[Ent any] interface {
+	// ListSorted lists a group of the entities matching filter, ordered by sort.
+	ListSorted(ctx context.Context, filter Filter, sort Sort, offset, limit int) ([]Ent, error)
+}
+
+// CursorLister can be implemented by an [EntityProvider] that can paginate its entities
+// with cursors instead of offsets: the listed entities don't shift when new ones are created.
+// The cursor is given with the cursor list query parameter, e.g.:
+//
+//	GET /entities/?cursor=…&page_size=10
+//
+// The next and previous cursors are sent in the Link response header.
+type CursorLister[Ent any] interface {
+	// ListCursor lists a group of at most limit entities at the position of the opaque cursor.
+	// An empty cursor lists the first entities.
+	// It returns the cursors of the next and the previous groups, empty if there is none.
+	ListCursor(ctx context.Context, cursor string, limit int) (ents []Ent, next, prev string, err error)
+}
+
+// Counter can be implemented by an [EntityProvider] that can count its entities.
+// The count is sent in the X-Total-Count list response header, and gives the last page
+// of the list.
+type Counter interface {
+	// Count counts the entities matching filter.
+	// filter is always empty if the provider doesn't implement [FilterLister] as well.
+	Count(ctx context.Context, filter Filter) (int, error)
+}
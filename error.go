@@ -1,6 +1,8 @@
 package rip
 
 import (
+	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"net/http"
@@ -120,7 +122,66 @@ func (e Error) Error() string {
 	return fmt.Sprintf("%d - %s - %s", e.Code, e.Detail, e.Source)
 }
 
+// Errors are several errors of a request, e.g. an [Error] per invalid field of an entity.
+// They are answered with the status code of the first one.
+type Errors struct {
+	XMLName xml.Name `json:"-" xml:"errors" yaml:"-" msgpack:"-"`
+
+	Errors []Error `json:"errors" xml:"error" yaml:"errors" msgpack:"errors"`
+}
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+func (e Errors) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
 func writeError(w http.ResponseWriter, accept string, err error, cfg entityRouteConfig) {
+	e := toError(err, cfg)
+
+	if errors.Is(err, encoding.ErrNoEncoderAvailable) {
+		e.Status = http.StatusNotAcceptable
+		e.Detail = fmt.Sprintf("Accept header cannot be satisfied: enabled content types for this route: %v", cfg.codecs.OrderedMimeTypes)
+		accept = strings.Join(cfg.codecs.Codecs[encoding.DefaultCodecKey].MimeTypes, "; ")
+	}
+
+	// if no acceptable codec is chosen, we will write to the client in the default codec available.
+	// There should be one at least, otherwise it would have paniced when configuring the route options
+	// codecs.
+	if accept == "" {
+		accept = encoding.DefaultCodecKey
+	}
+
+	encoder := encoding.AcceptEncoder(w, accept, encoding.EditOff, cfg.codecs)
+
+	var body any = e
+	var errs Errors
+	if errors.As(err, &errs) && len(errs.Errors) > 0 {
+		body = errs
+	}
+
+	w.WriteHeader(e.Status)
+	err = encoder.Encode(body)
+	if err != nil {
+		// We can't do anything, we need to make the HTTP server intercept the panic
+		panic(err)
+	}
+}
+
+// toError converts err to an Error with the HTTP status code it should be answered with.
+func toError(err error, cfg entityRouteConfig) Error {
 	var e Error
 	if !errors.As(err, &e) {
 		e = Error{
@@ -128,7 +189,12 @@ func writeError(w http.ResponseWriter, accept string, err error, cfg entityRoute
 		}
 	}
 
-	e.Source = extractErrorsSource(err)
+	// the source of the error can be documented by the error itself, or by
+	// implementing the ErrorSource* interfaces.
+	source := extractErrorsSource(err)
+	if source != (ErrorSource{}) {
+		e.Source = source
+	}
 
 	for statusError, s := range cfg.statusMap {
 		if errors.Is(err, statusError) {
@@ -139,7 +205,11 @@ func writeError(w http.ResponseWriter, accept string, err error, cfg entityRoute
 		e.Status = http.StatusInternalServerError
 	}
 
-	e.Detail = err.Error()
+	// if err is a bare Error, its Detail is already the best explanation
+	_, isError := err.(Error)
+	if !isError || e.Detail == "" {
+		e.Detail = err.Error()
+	}
 
 	var bre badRequestError
 	if e.Code == errorCodeBadQArg || errors.As(err, &bre) {
@@ -151,27 +221,28 @@ func writeError(w http.ResponseWriter, accept string, err error, cfg entityRoute
 		e.Status = http.StatusNotFound
 	}
 
-	if errors.Is(err, encoding.ErrNoEncoderAvailable) {
-		e.Status = http.StatusNotAcceptable
-		e.Detail = fmt.Sprintf("Accept header cannot be satisfied: enabled content types for this route: %v", cfg.codecs.OrderedMimeTypes)
-		accept = strings.Join(cfg.codecs.Codecs[encoding.DefaultCodecKey].MimeTypes, "; ")
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		e.Status = http.StatusRequestEntityTooLarge
 	}
 
-	// if no acceptable codec is chosen, we will write to the client in the default codec available.
-	// There should be one at least, otherwise it would have paniced when configuring the route options
-	// codecs.
-	if accept == "" {
-		accept = encoding.DefaultCodecKey
+	if errors.Is(err, context.DeadlineExceeded) {
+		// the provider didn't answer before the deadline of WithTimeout
+		e.Status = http.StatusGatewayTimeout
 	}
 
-	encoder := encoding.AcceptEncoder(w, accept, encoding.EditOff, cfg.codecs)
+	return e
+}
 
-	w.WriteHeader(e.Status)
-	err = encoder.Encode(e)
-	if err != nil {
-		// We can't do anything, we need to make the HTTP server intercept the panic
-		panic(err)
+// isNotFound tells if err means that an entity could not be found.
+func isNotFound(err error) bool {
+	var e Error
+	if errors.As(err, &e) && e.Code == ErrorCodeNotFound {
+		return true
 	}
+
+	var nfe notFoundError
+	return errors.As(err, &nfe)
 }
 
 type notFoundError struct {
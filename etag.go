@@ -0,0 +1,240 @@
+package rip
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// etagFunc computes the entity tag of the representation of an entity, see [representation].
+type etagFunc[Ent any] func(ent Ent, representation string) (string, error)
+
+// entityETagFunc uses the entity version if ep implements [EntityVersioner], or
+// a hash of the encoded entity otherwise.
+func entityETagFunc[Ent any](ep EntityProvider[Ent]) etagFunc[Ent] {
+	versioner, ok := ep.(EntityVersioner[Ent])
+	if ok {
+		return func(ent Ent, representation string) (string, error) {
+			return quoteETag(versioner.Version(ent)), nil
+		}
+	}
+
+	return hashETag[Ent]
+}
+
+// hashETag computes a strong entity tag from the JSON encoding of ent and from its
+// representation: the representations of an entity with different bytes, e.g. in JSON and
+// in XML, get different entity tags.
+func hashETag[Ent any](ent Ent, representation string) (string, error) {
+	b, err := json.Marshal(ent)
+	if err != nil {
+		return "", fmt.Errorf("compute entity tag: %w", err)
+	}
+
+	h := sha256.New()
+	h.Write(b)
+	h.Write([]byte{0})
+	h.Write([]byte(representation))
+
+	return quoteETag(hex.EncodeToString(h.Sum(nil)[:16])), nil
+}
+
+// representationParameters are the query parameters changing the representation of an entity.
+var representationParameters = []string{"fields", "include", "mode"}
+
+// representation identifies the representation of an entity in mediaType, changed by the
+// query parameters, e.g. application/json?fields=id%2Cname
+func representation(mediaType string, query url.Values) string {
+	projection := url.Values{}
+	for _, p := range representationParameters {
+		if query.Has(p) {
+			projection[p] = query[p]
+		}
+	}
+
+	if len(projection) == 0 {
+		return mediaType
+	}
+
+	return mediaType + "?" + projection.Encode()
+}
+
+// quoteETag makes version a valid entity tag if it is not already one.
+func quoteETag(version string) string {
+	if strings.HasPrefix(version, `"`) || strings.HasPrefix(version, `W/"`) {
+		return version
+	}
+
+	return `"` + strings.ReplaceAll(version, `"`, "") + `"`
+}
+
+// setETag sets the ETag header of the representation of ent in accept, for the request r.
+func setETag[Ent any](w http.ResponseWriter, r *http.Request, accept string, ent Ent, etag etagFunc[Ent]) error {
+	tag, err := etag(ent, representation(accept, r.URL.Query()))
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", tag)
+	return nil
+}
+
+// setLastModified sets the Last-Modified header if the entity has a field with
+// the `rip:"updated_at"` struct tag, or else with the `rip:"created_at"` one.
+func setLastModified(w http.ResponseWriter, ent any) {
+	updatedAt, ok := ripreflect.TaggedTime(ent, ripreflect.TagUpdatedAt)
+	if !ok {
+		updatedAt, ok = ripreflect.TaggedTime(ent, ripreflect.TagCreatedAt)
+	}
+	if !ok || updatedAt.IsZero() {
+		return
+	}
+
+	w.Header().Set("Last-Modified", updatedAt.UTC().Format(http.TimeFormat))
+}
+
+// notModified evaluates the If-None-Match and If-Modified-Since preconditions of a GET request
+// against the validators already set in the response header.
+// If-Modified-Since is ignored if If-None-Match is present, see RFC 9110 section 13.2.2.
+func notModified(r *http.Request, responseHeader http.Header) bool {
+	ifNoneMatch := r.Header.Values("If-None-Match")
+	if len(ifNoneMatch) > 0 {
+		etag := responseHeader.Get("ETag")
+		return etag != "" && etagMatch(ifNoneMatch, etag, true)
+	}
+
+	ifModifiedSince := r.Header.Get("If-Modified-Since")
+	lastModified := responseHeader.Get("Last-Modified")
+	if ifModifiedSince == "" || lastModified == "" {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		// an invalid date must be ignored
+		return false
+	}
+
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+
+	return !modified.After(since)
+}
+
+// writeNotModified answers a 304 Not Modified if the client already has the current
+// representation of the resource, and tells if it did.
+func writeNotModified(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if !notModified(r, w.Header()) {
+		return false
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// checkIfMatch evaluates the If-Match precondition of r against the current version of the
+// entity with this id. It only gets the entity if the request has a precondition.
+func checkIfMatch[Ent any](r *http.Request, id string, get getFunc[Ent], etag etagFunc[Ent], cfg entityRouteConfig) error {
+	if len(r.Header.Values("If-Match")) == 0 {
+		return checkPreconditionRequired(cfg)
+	}
+
+	ent, err := get(r.Context(), id)
+	if err != nil {
+		if isNotFound(err) {
+			// there is no current representation to match
+			return errPreconditionFailed
+		}
+		return fmt.Errorf("can not get original entity: %w", err)
+	}
+
+	return checkIfMatchEntity(r, ent, etag, cfg)
+}
+
+// checkIfMatchEntity evaluates the If-Match precondition of r against ent: the entity tag of
+// any of its representations in the media types of the route matches.
+func checkIfMatchEntity[Ent any](r *http.Request, ent Ent, etag etagFunc[Ent], cfg entityRouteConfig) error {
+	ifMatch := r.Header.Values("If-Match")
+	if len(ifMatch) == 0 {
+		return checkPreconditionRequired(cfg)
+	}
+
+	for _, mediaType := range cfg.codecs.OrderedMimeTypes {
+		current, err := etag(ent, representation(mediaType, r.URL.Query()))
+		if err != nil {
+			return err
+		}
+
+		if etagMatch(ifMatch, current, false) {
+			return nil
+		}
+	}
+
+	return errPreconditionFailed
+}
+
+func checkPreconditionRequired(cfg entityRouteConfig) error {
+	if !cfg.preconditionRequired {
+		return nil
+	}
+
+	return Error{
+		Status: http.StatusPreconditionRequired,
+		Detail: "this request must be conditional, use the If-Match header with the entity ETag",
+		Source: ErrorSource{
+			Header: "If-Match",
+		},
+	}
+}
+
+var errPreconditionFailed = Error{
+	Status: http.StatusPreconditionFailed,
+	Detail: "the entity has been modified: If-Match does not match the current entity ETag",
+	Source: ErrorSource{
+		Header: "If-Match",
+	},
+}
+
+// etagMatch tells if one of the entity tags of the header values matches current.
+// With a weak comparison, W/"1" matches "1", while with a strong comparison,
+// a weak entity tag never matches, see RFC 9110 section 8.8.3.2.
+func etagMatch(headerValues []string, current string, weak bool) bool {
+	currentIsWeak := strings.HasPrefix(current, "W/")
+	if !weak && currentIsWeak {
+		return false
+	}
+
+	for _, v := range headerValues {
+		for _, tag := range strings.Split(v, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" {
+				return true
+			}
+
+			if strings.HasPrefix(tag, "W/") {
+				if !weak {
+					continue
+				}
+				tag = strings.TrimPrefix(tag, "W/")
+			}
+
+			if tag == strings.TrimPrefix(current, "W/") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,242 @@
+package rip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dolanor/rip/encoding/json"
+	"github.com/dolanor/rip/encoding/xml"
+)
+
+func TestIfMatch(t *testing.T) {
+	up := newUserProvider()
+	up.mem["Jane"] = user{Name: "Jane", EmailAddress: "jane@example.com"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/users/", up, WithCodecs(json.Codec), WithPreconditionRequired()))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	c := s.Client()
+
+	do := func(t *testing.T, method, ifMatch, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, s.URL+"/users/Jane", strings.NewReader(body))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+
+		resp, err := c.Do(req)
+		panicErr(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := do(t, http.MethodGet, "", "")
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag on GET")
+	}
+
+	t.Run("missing precondition", func(t *testing.T) {
+		resp := do(t, http.MethodPut, "", `{"name": "Jane", "email_address": "jane@example.org"}`)
+		if resp.StatusCode != http.StatusPreconditionRequired {
+			t.Fatal("put status code is not 428:", resp.StatusCode)
+		}
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		resp := do(t, http.MethodPut, `"nope", W/`+etag, `{"name": "Jane", "email_address": "jane@example.org"}`)
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			t.Fatal("put status code is not 412:", resp.StatusCode)
+		}
+	})
+
+	newETag := etag
+	t.Run("match", func(t *testing.T) {
+		resp := do(t, http.MethodPut, etag, `{"name": "Jane", "email_address": "jane@example.org"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("put status code is not 200:", resp.StatusCode)
+		}
+
+		newETag = resp.Header.Get("ETag")
+		if newETag == etag || newETag == "" {
+			t.Fatal("ETag did not change after update:", newETag)
+		}
+	})
+
+	t.Run("stale delete", func(t *testing.T) {
+		resp := do(t, http.MethodDelete, etag, "")
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			t.Fatal("delete status code is not 412:", resp.StatusCode)
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		resp := do(t, http.MethodDelete, newETag, "")
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatal("delete status code is not 204:", resp.StatusCode)
+		}
+	})
+
+	t.Run("any on deleted entity", func(t *testing.T) {
+		resp := do(t, http.MethodDelete, "*", "")
+		if resp.StatusCode != http.StatusPreconditionFailed {
+			t.Fatal("delete status code is not 412:", resp.StatusCode)
+		}
+	})
+}
+
+type versionedUserProvider struct {
+	*UserProvider
+}
+
+func (versionedUserProvider) Version(u *user) string {
+	return u.EmailAddress
+}
+
+func TestEntityVersioner(t *testing.T) {
+	up := versionedUserProvider{newUserProvider()}
+	up.Create(context.Background(), &user{Name: "Jane", EmailAddress: "jane@example.com"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/users/", up, WithCodecs(json.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	resp, err := s.Client().Get(s.URL + "/users/Jane")
+	panicErr(t, err)
+	resp.Body.Close()
+
+	if resp.Header.Get("ETag") != `"jane@example.com"` {
+		t.Fatal("ETag is not the entity version:", resp.Header.Get("ETag"))
+	}
+}
+
+type article struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at" rip:"updated_at"`
+}
+
+func TestConditionalGet(t *testing.T) {
+	updatedAt := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	ap := newMemoryProvider(article{ID: "1", Title: "REST in peace", UpdatedAt: updatedAt})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/articles/", ap, WithCodecs(json.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	get := func(t *testing.T, path string, header http.Header) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+		panicErr(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	resp := get(t, "/articles/1", nil)
+	etag := resp.Header.Get("ETag")
+	if resp.Header.Get("Last-Modified") != "Fri, 01 Mar 2024 12:00:00 GMT" {
+		t.Fatal("bad Last-Modified:", resp.Header.Get("Last-Modified"))
+	}
+
+	cases := map[string]struct {
+		path   string
+		header http.Header
+		status int
+	}{
+		"if-none-match":                  {"/articles/1", http.Header{"If-None-Match": {`"other", ` + etag}}, http.StatusNotModified},
+		"if-none-match weak":             {"/articles/1", http.Header{"If-None-Match": {"W/" + etag}}, http.StatusNotModified},
+		"if-none-match changed":          {"/articles/1", http.Header{"If-None-Match": {`"other"`}}, http.StatusOK},
+		"if-modified-since":              {"/articles/1", http.Header{"If-Modified-Since": {"Fri, 01 Mar 2024 12:00:00 GMT"}}, http.StatusNotModified},
+		"if-modified-since before":       {"/articles/1", http.Header{"If-Modified-Since": {"Fri, 01 Mar 2024 11:59:59 GMT"}}, http.StatusOK},
+		"if-none-match has precedence":   {"/articles/1", http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {"Fri, 01 Mar 2024 12:00:00 GMT"}}, http.StatusOK},
+		"list without validator":         {"/articles/", nil, http.StatusOK},
+		"list if-none-match any":         {"/articles/", http.Header{"If-None-Match": {"*"}}, http.StatusNotModified},
+		"list ignores if-modified-since": {"/articles/", http.Header{"If-Modified-Since": {"Fri, 01 Mar 2024 12:00:00 GMT"}}, http.StatusOK},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp := get(t, c.path, c.header)
+			if resp.StatusCode != c.status {
+				t.Fatalf("status code is not %d: %d", c.status, resp.StatusCode)
+			}
+			if resp.Header.Get("ETag") == "" {
+				t.Fatal("missing ETag")
+			}
+		})
+	}
+
+	t.Run("list etag", func(t *testing.T) {
+		resp := get(t, "/articles/", nil)
+		resp = get(t, "/articles/", http.Header{"If-None-Match": {resp.Header.Get("ETag")}})
+		if resp.StatusCode != http.StatusNotModified {
+			t.Fatal("list status code is not 304:", resp.StatusCode)
+		}
+	})
+}
+
+func TestRepresentationETag(t *testing.T) {
+	ap := newMemoryProvider(article{ID: "1", Title: "REST in peace"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/articles/", ap, WithCodecs(json.Codec, xml.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	do := func(t *testing.T, method, path string, header http.Header, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		panicErr(t, err)
+		for k, v := range header {
+			req.Header[k] = v
+		}
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	jsonETag := do(t, http.MethodGet, "/articles/1", http.Header{"Accept": {"application/json"}}, "").Header.Get("ETag")
+	xmlETag := do(t, http.MethodGet, "/articles/1", http.Header{"Accept": {"application/xml"}}, "").Header.Get("ETag")
+	fieldsETag := do(t, http.MethodGet, "/articles/1?fields=title", http.Header{"Accept": {"application/json"}}, "").Header.Get("ETag")
+	if jsonETag == "" || jsonETag == xmlETag || jsonETag == fieldsETag || xmlETag == fieldsETag {
+		t.Fatal("the representations don't have their own ETag:", jsonETag, xmlETag, fieldsETag)
+	}
+
+	t.Run("if-none-match other representation", func(t *testing.T) {
+		resp := do(t, http.MethodGet, "/articles/1", http.Header{"Accept": {"application/xml"}, "If-None-Match": {jsonETag}}, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+	})
+
+	t.Run("if-match other representation", func(t *testing.T) {
+		header := http.Header{
+			"Accept":       {"application/json"},
+			"Content-Type": {"application/json"},
+			"If-Match":     {xmlETag},
+		}
+		resp := do(t, http.MethodPut, "/articles/1", header, `{"id": "1", "title": "REST assured"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package rip
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// entityField finds the field of ent at fieldPath, e.g.: address/city or tags/0.
+// The path segments are the struct field names, case-insensitively or from their
+// json struct tag, and the indexes of slices and arrays.
+// To write the field, the nil pointers on the path are allocated, and the read-only fields
+// are rejected. To read it, the write-only fields are not found.
+func entityField(ent reflect.Value, fieldPath string, write bool) (reflect.Value, error) {
+	v := ent
+	segments := strings.Split(fieldPath, "/")
+	for i, segment := range segments {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !write {
+					return reflect.Value{}, fieldNotFoundError(segments[:i+1])
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := ripreflect.FieldByName(v.Type(), segment)
+			if !ok || !write && ripreflect.HasRIPTag(f, ripreflect.TagWriteOnly) {
+				return reflect.Value{}, fieldNotFoundError(segments[:i+1])
+			}
+			if write && ripreflect.IsReadOnly(f) {
+				return reflect.Value{}, readOnlyFieldError(strings.Join(segments[:i+1], "/"))
+			}
+			v = v.FieldByIndex(f.Index)
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= v.Len() {
+				return reflect.Value{}, fieldNotFoundError(segments[:i+1])
+			}
+			v = v.Index(index)
+		default:
+			return reflect.Value{}, fieldNotFoundError(segments[:i+1])
+		}
+	}
+
+	return v, nil
+}
+
+func fieldNotFoundError(segments []string) Error {
+	return Error{
+		Code:   ErrorCodeNotFound,
+		Status: http.StatusNotFound,
+		Detail: fmt.Sprintf("field %q not found", strings.Join(segments, "/")),
+		Source: ErrorSource{
+			Pointer: "/" + strings.Join(segments, "/"),
+		},
+	}
+}
+
+// decodeField decodes the data from r, with the content type, into the field of ent at fieldPath.
+func decodeField[Ent any](ent *Ent, fieldPath string, r io.Reader, contentType string, cfg entityRouteConfig) error {
+	field, err := entityField(reflect.ValueOf(ent).Elem(), fieldPath, true)
+	if err != nil {
+		return err
+	}
+
+	decoder, err := contentTypeDecoder(r, contentType, cfg)
+	if err != nil {
+		return err
+	}
+
+	// the field is decoded in its own type, then set: a partially decoded value
+	// doesn't change the entity.
+	v := reflect.New(field.Type())
+	err = decoder.Decode(v.Interface())
+	if err != nil {
+		var e Error
+		if errors.As(decodeError(err, r, strings.Split(fieldPath, "/")...), &e) {
+			return e
+		}
+
+		return Error{
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("can not decode field %q: %v", fieldPath, err),
+			Source: ErrorSource{
+				Pointer: "/" + fieldPath,
+			},
+		}
+	}
+
+	field.Set(v.Elem())
+
+	return nil
+}
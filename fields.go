@@ -0,0 +1,98 @@
+package rip
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/dolanor/rip/encoding"
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// sparseFields encodes only some fields of the entities, selected with the fields
+// query parameter:
+//
+//	?fields=name,email_address
+//
+// The id field of the entity is always kept.
+type sparseFields[Ent any] struct {
+	// fields are the indexes of the selected fields in the Ent struct.
+	fields []int
+}
+
+// parseSparseFields parses the fields query parameter.
+// The fields are looked up in Ent like the field filters.
+// It returns nil without the fields query parameter: all the fields are encoded.
+func parseSparseFields[Ent any](query url.Values) (*sparseFields[Ent], error) {
+	if !query.Has("fields") {
+		return nil, nil
+	}
+
+	var ent Ent
+	structType, ok := ripreflect.StructType(reflect.TypeOf(ent))
+	if !ok {
+		return nil, nil
+	}
+
+	var selected []int
+	for _, v := range query["fields"] {
+		for _, name := range strings.Split(v, ",") {
+			field, ok := ripreflect.FieldByName(structType, strings.TrimSpace(name))
+			if !ok {
+				return nil, Error{
+					Status: http.StatusBadRequest,
+					Detail: fmt.Sprintf("unknown field %q", name),
+					Source: ErrorSource{
+						Parameter: "fields",
+					},
+				}
+			}
+
+			if isWriteOnly(field) {
+				return nil, Error{
+					Status: http.StatusBadRequest,
+					Detail: fmt.Sprintf("field %q is write-only", name),
+					Source: ErrorSource{
+						Parameter: "fields",
+					},
+				}
+			}
+
+			selected = append(selected, field.Index[0])
+		}
+	}
+
+	sf := &sparseFields[Ent]{}
+	for i := 0; i < structType.NumField(); i++ {
+		f := structType.Field(i)
+		isID := f.Name == "ID" || ripreflect.HasRIPIDField(f)
+		if slices.Contains(selected, i) || isID {
+			sf.fields = append(sf.fields, i)
+		}
+	}
+
+	return sf, nil
+}
+
+// isProtoMessage tells if structType is a protobuf generated message.
+// We don't want to depend on the protobuf module just for the proto.Message interface.
+func isProtoMessage(structType reflect.Type) bool {
+	_, ok := reflect.PointerTo(structType).MethodByName("ProtoReflect")
+	return ok
+}
+
+// shape returns the shape of the entities with only the selected fields.
+func (sf *sparseFields[Ent]) shape() encoding.Shape {
+	if sf == nil {
+		return encoding.Shape{}
+	}
+
+	return encoding.Shape{
+		Keep: func(f reflect.StructField) bool {
+			return slices.Contains(sf.fields, f.Index[0])
+		},
+	}
+}
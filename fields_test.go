@@ -0,0 +1,125 @@
+package rip
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dolanor/rip/encoding/html"
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+	"github.com/dolanor/rip/encoding/xml"
+)
+
+func TestSparseFields(t *testing.T) {
+	up := newUserProvider()
+	up.mem["Jane"] = user{Name: "Jane", EmailAddress: "jane@example.com", BirthDate: time.Date(1990, time.May, 4, 0, 0, 0, 0, time.UTC)}
+	up.mem["John"] = user{Name: "John", EmailAddress: "john@example.com", BirthDate: time.Date(1985, time.June, 1, 0, 0, 0, 0, time.UTC)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/users/", up, WithCodecs(jsoncodec.Codec, xml.Codec, html.NewEntityCodec("/users/"))))
+	mux.HandleFunc(HandleEntities("/accounts/", newMemoryProvider(account{ID: "1", Name: "Jane", Password: "secret"}), WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	get := func(t *testing.T, path, accept string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, s.URL+path, nil)
+		panicErr(t, err)
+		req.Header.Set("Accept", accept)
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		panicErr(t, err)
+		return resp, string(b)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		resp, body := get(t, "/users/Jane?fields=EmailAddress", "application/json")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+
+		var got map[string]any
+		err := json.Unmarshal([]byte(body), &got)
+		panicErr(t, err)
+
+		want := map[string]any{"name": "Jane", "email_address": "jane@example.com"}
+		if len(got) != len(want) || got["name"] != want["name"] || got["email_address"] != want["email_address"] {
+			t.Fatalf("encoded fields are not %v: %v", want, got)
+		}
+	})
+
+	t.Run("json list", func(t *testing.T) {
+		resp, body := get(t, "/users/?fields=birth_date", "application/json")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+
+		var got []map[string]any
+		err := json.Unmarshal([]byte(body), &got)
+		panicErr(t, err)
+
+		if len(got) != 2 {
+			t.Fatal("listed entities are not 2:", len(got))
+		}
+		for _, u := range got {
+			_, hasEmail := u["email_address"]
+			if len(u) != 2 || hasEmail {
+				t.Fatal("encoded fields are not name and birth_date:", u)
+			}
+		}
+	})
+
+	t.Run("xml", func(t *testing.T) {
+		_, body := get(t, "/users/Jane?fields=email_address", "application/xml")
+		want := "<user><name>Jane</name><email_address>jane@example.com</email_address></user>"
+		if body != want {
+			t.Fatalf("xml is not %q: %q", want, body)
+		}
+	})
+
+	t.Run("html", func(t *testing.T) {
+		_, body := get(t, "/users/?fields=email_address", "text/html")
+		if !strings.Contains(body, ">user</a>") || !strings.Contains(body, "jane@example.com") {
+			t.Fatal("html does not show the user emails:", body)
+		}
+		if strings.Contains(body, "BirthDate") || strings.Contains(body, "XMLName") {
+			t.Fatal("html shows unselected fields:", body)
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		resp, body := get(t, "/users/Jane?fields=email_address,password", "application/json")
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatal("status code is not 400:", resp.StatusCode)
+		}
+
+		var e Error
+		err := json.Unmarshal([]byte(body), &e)
+		panicErr(t, err)
+		if e.Source.Parameter != "fields" {
+			t.Fatal("error parameter is not fields:", e.Source.Parameter)
+		}
+	})
+
+	t.Run("write-only field", func(t *testing.T) {
+		resp, body := get(t, "/accounts/1?fields=name,password", "application/json")
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatal("status code is not 400:", resp.StatusCode, body)
+		}
+
+		var e Error
+		err := json.Unmarshal([]byte(body), &e)
+		panicErr(t, err)
+		if e.Source.Parameter != "fields" {
+			t.Fatal("error parameter is not fields:", e.Source.Parameter)
+		}
+	})
+}
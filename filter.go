@@ -0,0 +1,221 @@
+package rip
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// FilterOperator is the comparison between an entity field and the values of a [FilterCondition].
+type FilterOperator string
+
+const (
+	// FilterEqual matches fields equal to the value: ?name=Jane or ?name[eq]=Jane
+	FilterEqual FilterOperator = "eq"
+	// FilterNotEqual matches fields different from the value: ?name[ne]=Jane
+	FilterNotEqual FilterOperator = "ne"
+	// FilterGreater matches fields greater than the value: ?age[gt]=30
+	FilterGreater FilterOperator = "gt"
+	// FilterGreaterOrEqual matches fields greater than or equal to the value: ?age[gte]=30
+	FilterGreaterOrEqual FilterOperator = "gte"
+	// FilterLess matches fields less than the value: ?age[lt]=30
+	FilterLess FilterOperator = "lt"
+	// FilterLessOrEqual matches fields less than or equal to the value: ?age[lte]=30
+	FilterLessOrEqual FilterOperator = "lte"
+	// FilterIn matches fields equal to one of the comma separated values: ?status[in]=a,b
+	FilterIn FilterOperator = "in"
+	// FilterNotIn matches fields equal to none of the comma separated values: ?status[nin]=a,b
+	FilterNotIn FilterOperator = "nin"
+)
+
+var filterOperators = []FilterOperator{
+	FilterEqual,
+	FilterNotEqual,
+	FilterGreater,
+	FilterGreaterOrEqual,
+	FilterLess,
+	FilterLessOrEqual,
+	FilterIn,
+	FilterNotIn,
+}
+
+// FilterCondition is a condition on a field of the listed entities.
+type FilterCondition struct {
+	// Field is the name of the entity struct field, e.g. "EmailAddress".
+	Field string
+
+	// Operator compares the field with the values.
+	Operator FilterOperator
+
+	// Values are the values to compare the field with, as found in the query.
+	// They have already been validated against the field type: there are several
+	// values for [FilterIn] and [FilterNotIn], and exactly one otherwise.
+	Values []string
+}
+
+// Filter selects the entities matching all its conditions.
+type Filter []FilterCondition
+
+// Match tells if ent matches all the conditions of the filter.
+// It can be used by an entity provider filtering its entities in memory.
+func (f Filter) Match(ent any) (bool, error) {
+	for _, c := range f {
+		field, ok := ripreflect.StructField(ent, c.Field)
+		if !ok {
+			return false, fmt.Errorf("filter: no field %q in %T", c.Field, ent)
+		}
+
+		ok, err := c.match(field)
+		if err != nil {
+			return false, err
+		}
+
+		if !ok {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (c FilterCondition) match(field reflect.Value) (bool, error) {
+	var comparisons []int
+	for _, v := range c.Values {
+		value, err := ripreflect.ParseValue(field.Type(), v)
+		if err != nil {
+			return false, fmt.Errorf("filter on field %q: %w", c.Field, err)
+		}
+		comparisons = append(comparisons, ripreflect.Compare(field, value))
+	}
+
+	if len(comparisons) == 0 {
+		return false, fmt.Errorf("filter on field %q: no value", c.Field)
+	}
+
+	switch c.Operator {
+	case FilterEqual:
+		return comparisons[0] == 0, nil
+	case FilterNotEqual:
+		return comparisons[0] != 0, nil
+	case FilterGreater:
+		return comparisons[0] > 0, nil
+	case FilterGreaterOrEqual:
+		return comparisons[0] >= 0, nil
+	case FilterLess:
+		return comparisons[0] < 0, nil
+	case FilterLessOrEqual:
+		return comparisons[0] <= 0, nil
+	case FilterIn:
+		return slices.Contains(comparisons, 0), nil
+	case FilterNotIn:
+		return !slices.Contains(comparisons, 0), nil
+	default:
+		return false, fmt.Errorf("filter on field %q: unknown operator %q", c.Field, c.Operator)
+	}
+}
+
+// reservedQueryParameters are the list query parameters that are not field filters.
+var reservedQueryParameters = []string{"page", "page_size", "mode", "sort", "fields", "cursor", "include", "include_deleted"}
+
+// parseFilter parses the field filters of a list query, e.g.:
+//
+//	?name=foo&age[gt]=30&status[in]=a,b
+//
+// The fields are looked up in Ent, by their Go name (case-insensitively) or their JSON name.
+func parseFilter[Ent any](query url.Values) (Filter, error) {
+	var ent Ent
+	entType := reflect.TypeOf(ent)
+
+	var filter Filter
+	for _, key := range filterKeys(query) {
+		filterErr := func(format string, args ...any) error {
+			return Error{
+				Status: http.StatusBadRequest,
+				Detail: fmt.Sprintf(format, args...),
+				Source: ErrorSource{
+					Parameter: key,
+				},
+			}
+		}
+
+		name, op, err := parseFilterKey(key)
+		if err != nil {
+			return nil, filterErr("%v", err)
+		}
+
+		field, ok := ripreflect.FieldByName(entType, name)
+		if !ok {
+			return nil, filterErr("unknown field %q", name)
+		}
+
+		if isWriteOnly(field) {
+			// the filter would reveal the hidden values
+			return nil, filterErr("field %q is write-only", name)
+		}
+
+		if !ripreflect.IsComparable(field.Type) {
+			return nil, filterErr("field %q can not be filtered", name)
+		}
+
+		for _, v := range query[key] {
+			values := []string{v}
+			if op == FilterIn || op == FilterNotIn {
+				values = strings.Split(v, ",")
+			}
+
+			for _, value := range values {
+				_, err := ripreflect.ParseValue(field.Type, value)
+				if err != nil {
+					return nil, filterErr("bad value for field %q: %v", name, err)
+				}
+			}
+
+			filter = append(filter, FilterCondition{
+				Field:    field.Name,
+				Operator: op,
+				Values:   values,
+			})
+		}
+	}
+
+	return filter, nil
+}
+
+// filterKeys returns the sorted names of the field filter query parameters.
+func filterKeys(query url.Values) []string {
+	var keys []string
+	for k := range query {
+		if !slices.Contains(reservedQueryParameters, k) {
+			keys = append(keys, k)
+		}
+	}
+	// map iteration is random, but the providers should get a stable filter
+	slices.Sort(keys)
+
+	return keys
+}
+
+// parseFilterKey splits a filter query parameter name as "age[gt]" in its field and operator.
+func parseFilterKey(key string) (field string, op FilterOperator, err error) {
+	field, rest, hasOp := strings.Cut(key, "[")
+	if !hasOp {
+		return field, FilterEqual, nil
+	}
+
+	opStr, ok := strings.CutSuffix(rest, "]")
+	if !ok || field == "" {
+		return "", "", fmt.Errorf("malformed filter %q", key)
+	}
+
+	op = FilterOperator(opStr)
+	if !slices.Contains(filterOperators, op) {
+		return "", "", fmt.Errorf("unknown filter operator %q", opStr)
+	}
+
+	return field, op, nil
+}
@@ -0,0 +1,126 @@
+package rip
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+type product struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Price  int    `json:"price"`
+	Status string `json:"status"`
+	Cost   int    `json:"cost,omitempty" rip:"writeonly"`
+}
+
+// filterMemoryProvider filters its entities in memory.
+type filterMemoryProvider[Ent any] struct {
+	*memoryProvider[Ent]
+}
+
+func (fp filterMemoryProvider[Ent]) ListFiltered(ctx context.Context, filter Filter, offset, limit int) ([]Ent, error) {
+	all, err := fp.List(ctx, 0, len(fp.mem))
+	if err != nil {
+		return nil, err
+	}
+
+	ents := []Ent{}
+	for _, e := range all {
+		ok, err := filter.Match(e)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ents = append(ents, e)
+		}
+	}
+
+	offset = min(offset, len(ents))
+	return ents[offset:min(offset+limit, len(ents))], nil
+}
+
+func newProductProvider() *memoryProvider[product] {
+	return newMemoryProvider(
+		product{ID: "1", Name: "chair", Price: 30, Status: "available", Cost: 12},
+		product{ID: "2", Name: "table", Price: 120, Status: "sold"},
+		product{ID: "3", Name: "lamp", Price: 15, Status: "available"},
+		product{ID: "4", Name: "sofa", Price: 450, Status: "reserved"},
+	)
+}
+
+func TestListFilter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/products/", filterMemoryProvider[product]{newProductProvider()}, WithCodecs(jsoncodec.Codec)))
+	mux.HandleFunc(HandleEntities("/unfiltered/", newProductProvider(), WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	cases := map[string]struct {
+		path      string
+		query     string
+		status    int
+		ids       []string
+		parameter string
+	}{
+		"no filter":           {query: "", status: http.StatusOK, ids: []string{"1", "2", "3", "4"}},
+		"equal":               {query: "?name=lamp", status: http.StatusOK, ids: []string{"3"}},
+		"equal operator":      {query: "?Name[eq]=lamp", status: http.StatusOK, ids: []string{"3"}},
+		"greater":             {query: "?price[gt]=30", status: http.StatusOK, ids: []string{"2", "4"}},
+		"range":               {query: "?price[gte]=30&price[lt]=450", status: http.StatusOK, ids: []string{"1", "2"}},
+		"in":                  {query: "?status[in]=sold,reserved", status: http.StatusOK, ids: []string{"2", "4"}},
+		"not in":              {query: "?status[nin]=sold,reserved&page_size=1", status: http.StatusOK, ids: []string{"1"}},
+		"no match":            {query: "?name[ne]=chair&status=available&price[gt]=20", status: http.StatusOK, ids: []string{}},
+		"unknown field":       {query: "?color=red", status: http.StatusBadRequest, parameter: "color"},
+		"write-only field":    {query: "?cost[gt]=10", status: http.StatusBadRequest, parameter: "cost[gt]"},
+		"unknown operator":    {query: "?price[like]=3", status: http.StatusBadRequest, parameter: "price[like]"},
+		"malformed":           {query: "?price[gt=3", status: http.StatusBadRequest, parameter: "price[gt"},
+		"bad value":           {query: "?price[gt]=cheap", status: http.StatusBadRequest, parameter: "price[gt]"},
+		"filter unsupported":  {path: "/unfiltered/", query: "?name=lamp", status: http.StatusOK, ids: []string{"1", "2", "3", "4"}},
+		"unrelated parameter": {path: "/unfiltered/", query: "?utm_source=x&_=123", status: http.StatusOK, ids: []string{"1", "2", "3", "4"}},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := "/products/"
+			if c.path != "" {
+				path = c.path
+			}
+
+			resp, err := s.Client().Get(s.URL + path + c.query)
+			panicErr(t, err)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != c.status {
+				t.Fatalf("status code is not %d: %d", c.status, resp.StatusCode)
+			}
+
+			if c.status != http.StatusOK {
+				var e Error
+				err = json.NewDecoder(resp.Body).Decode(&e)
+				panicErr(t, err)
+				if e.Source.Parameter != c.parameter {
+					t.Fatalf("error parameter is not %q: %q", c.parameter, e.Source.Parameter)
+				}
+				return
+			}
+
+			var products []product
+			err = json.NewDecoder(resp.Body).Decode(&products)
+			panicErr(t, err)
+
+			ids := []string{}
+			for _, p := range products {
+				ids = append(ids, p.ID)
+			}
+			if !reflect.DeepEqual(ids, c.ids) {
+				t.Fatalf("listed ids are not %v: %v", c.ids, ids)
+			}
+		})
+	}
+}
@@ -5,10 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
 	"path"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -34,16 +35,39 @@ type Middleware = func(http.HandlerFunc) http.HandlerFunc
 
 // HandleEntities associates an urlPath with an entity provider, and handles all HTTP requests in a RESTful way:
 //
-//	POST   /entities/    : creates the entity
-//	GET    /entities/:id : get the entity
-//	PUT    /entities/:id : updates the entity (needs to pass the full entity data)
-//	DELETE /entities/:id : deletes the entity
-//	GET    /entities/    : lists the entities (accepts page and page_size query param)
+//	POST   /entities/    : creates the entity (the Location response header links to it)
+//	POST   /entities/_batch : creates, updates and deletes entities in one request (transactional with a [Batcher])
+//	GET    /entities/:id : get the entity (accepts the fields query param to only get some fields, and include with [WithReferences])
+//	PUT    /entities/:id : updates the entity (needs to pass the full entity data), or creates it with [WithUpsert]
+//	PATCH  /entities/:id : partially updates the entity (accepts merge patch and JSON patch documents)
+//	DELETE /entities/:id : deletes the entity (soft deletes it if it has a `rip:"deleted_at"` field)
+//	POST   /entities/:id/_restore : restores the soft deleted entity, with a [Restorer]
+//	GET    /entities/    : lists the entities (accepts page and page_size query param, fields, field filters with a [FilterLister], sort with a [SortLister], cursor with a [CursorLister], and include with [WithReferences])
 //
-// It also handles fields
+// HEAD is handled like GET, without the response body. OPTIONS describes the allowed methods
+// and the accepted content types.
+//
+// The entities are validated with their `validate` struct tags and the [Validator] interface
+// before being created or updated: the invalid ones get a 422 Unprocessable Entity error.
+//
+// The fields with the `rip:"readonly"` struct tag are ignored in the request bodies, and the
+// ones with the `rip:"writeonly"` struct tag are left out of the responses.
+// The time.Time fields with the `rip:"created_at"` and `rip:"updated_at"` struct tags are set
+// on creation and update, they give the Last-Modified response header.
+//
+// The soft deleted entities are hidden, unless the include_deleted=true query parameter is set
+// on GET, see [IncludeDeleted].
+//
+// It also handles fields, nested ones and slice elements too
 //
 //	GET    /entities/:id/name : get only the name field of the entity
 //	PUT    /entities/:id/name : updates only the name entity field
+//	PUT    /entities/:id/address/city : updates only the city of the address of the entity
+//	PUT    /entities/:id/tags/0 : updates only the first tag of the entity
+//
+// and the routes nested with [WithNestedRoute]
+//
+//	GET    /entities/:id/albums/ : lists the albums of the entity
 func HandleEntities[
 	Ent any,
 	EP EntityProvider[Ent],
@@ -60,51 +84,156 @@ func HandleEntities[
 
 	cfg = setEntityRouteConfigDefaults(cfg)
 
-	return handleEntityWithPath(urlPath, ep.Create, ep.Get, ep.Update, ep.Delete, ep.List, cfg)
+	return handleEntityWithPath[Ent](urlPath, ep, cfg)
 }
 
 type (
 	createFunc[Ent any] func(ctx context.Context, ent Ent) (Ent, error)
 	getFunc[Ent any]    func(ctx context.Context, id string) (Ent, error)
-	updateFunc[Ent any] func(ctx context.Context, ent Ent) error
+	updateFunc[Ent any] func(ctx context.Context, ent Ent) (Ent, error)
 	deleteFunc          func(ctx context.Context, id string) error
 	listFunc[Ent any]   func(ctx context.Context, limit, offset int) ([]Ent, error)
+
+	listFilteredFunc[Ent any] func(ctx context.Context, filter Filter, offset, limit int) ([]Ent, error)
+	listSortedFunc[Ent any]   func(ctx context.Context, filter Filter, sort Sort, offset, limit int) ([]Ent, error)
+	listCursorFunc[Ent any]   func(ctx context.Context, cursor string, limit int) (ents []Ent, next, prev string, err error)
+	countFunc                 func(ctx context.Context, filter Filter) (int, error)
 )
 
+// providerUpdate adapts the Update method of an entity provider to return the updated entity,
+// as the handlers can change it before it is updated (e.g. its timestamps).
+func providerUpdate[Ent any](update func(ctx context.Context, ent Ent) error) updateFunc[Ent] {
+	return func(ctx context.Context, ent Ent) (Ent, error) {
+		return ent, update(ctx, ent)
+	}
+}
+
+// entityListers are the list functions of an entity provider.
+// The optional ones are nil if the provider doesn't implement them.
+type entityListers[Ent any] struct {
+	list     listFunc[Ent]
+	filtered listFilteredFunc[Ent]
+	sorted   listSortedFunc[Ent]
+	cursor   listCursorFunc[Ent]
+	count    countFunc
+}
+
+func newEntityListers[Ent any](ep EntityProvider[Ent]) entityListers[Ent] {
+	l := entityListers[Ent]{
+		list: ep.List,
+	}
+
+	if fl, ok := ep.(FilterLister[Ent]); ok {
+		l.filtered = fl.ListFiltered
+	}
+
+	if sl, ok := ep.(SortLister[Ent]); ok {
+		l.sorted = sl.ListSorted
+	}
+
+	if cl, ok := ep.(CursorLister[Ent]); ok {
+		l.cursor = cl.ListCursor
+	}
+
+	if c, ok := ep.(Counter); ok {
+		l.count = c.Count
+	}
+
+	return l
+}
+
 func handleEntityWithPath[Ent any](
 	urlPath string,
-	create createFunc[Ent],
-	get getFunc[Ent],
-	update updateFunc[Ent],
-	deleteFn deleteFunc,
-	list listFunc[Ent],
+	ep EntityProvider[Ent],
 	cfg entityRouteConfig,
 ) (path string, handler http.HandlerFunc) {
+	validate := newValidator[Ent]()
+	create := readOnlyCreate(validatedCreate(timestampedCreate(ep.Create), validate))
+	get := ep.Get
+	update := readOnlyUpdate(validatedUpdate(timestampedUpdate(providerUpdate(ep.Update)), validate), ep.Get)
+	deleteFn := ep.Delete
+	restore := entityRestore(ep)
+	list := newEntityListers(ep)
+	etag := entityETagFunc(ep)
+
+	batch := sequentialBatch(create, update, deleteFn, cfg)
+	if b, ok := ep.(Batcher[Ent]); ok {
+		batch = readOnlyBatch(validatedBatch(timestampedBatch(b.Batch), validate), get)
+	}
+
 	handler = func(w http.ResponseWriter, r *http.Request) {
+		urlPath := mountedPath(r.Context(), urlPath)
+
+		parentID, nested, ok := findNestedRoute(urlPath, r.URL.Path, cfg.nestedRoutes)
+		if ok {
+			handleNested(w, r, urlPath, parentID, nested, get, cfg)
+			return
+		}
+
+		allowed := allowedMethods(urlPath, r.URL.Path, restore != nil)
+		if !slices.Contains(allowed, r.Method) {
+			badMethodHandler(w, r, allowed, cfg)
+			return
+		}
+
+		r, cancel := withTimeout(r, entityOperation(urlPath, r), cfg)
+		defer cancel()
+
 		switch r.Method {
 		case http.MethodPost:
-			handleCreate(r.Method, urlPath, create, cfg)(w, r)
-		case http.MethodGet:
+			switch {
+			case isBatchPath(urlPath, r.URL.Path):
+				handleBatch(r.Method, batch, cfg)(w, r)
+			case isRestorePath(urlPath, r.URL.Path):
+				handleRestore(urlPath, r.Method, restore, get, etag, cfg)(w, r)
+			default:
+				handleCreate(r.Method, urlPath, create, etag, cfg)(w, r)
+			}
+		case http.MethodGet, http.MethodHead:
+			if r.Method == http.MethodHead {
+				// HEAD is a GET without the response body
+				w = headResponseWriter{ResponseWriter: w}
+			}
+
 			_, _, _, _, accept, editMode, err := getIDAndEditMode(w, r, r.Method, urlPath, cfg)
 			if err != nil {
 				writeError(w, accept, err, cfg)
 				return
 			}
 
+			includeDeleted, err := parseIncludeDeleted[Ent](r.URL.Query())
+			if err != nil {
+				writeError(w, accept, err, cfg)
+				return
+			}
+			if includeDeleted {
+				r = r.WithContext(withIncludeDeleted(r.Context()))
+			}
+
 			if urlPath == r.URL.Path && editMode == encoding.EditOff {
 				handleListAll(urlPath, r.Method, list, cfg)(w, r)
 				return
 			}
-			handleGet(urlPath, r.Method, get, cfg)(w, r)
+			handleGet(urlPath, r.Method, get, etag, cfg)(w, r)
 		case http.MethodPut:
-			updatePathID(urlPath, r.Method, update, get, cfg)(w, r)
+			updatePathID(urlPath, r.Method, update, get, create, etag, cfg)(w, r)
+		case http.MethodPatch:
+			patchPathID(urlPath, r.Method, update, get, etag, cfg)(w, r)
 		case http.MethodDelete:
-			deletePathID(urlPath, r.Method, deleteFn, cfg)(w, r)
-		default:
-			badMethodHandler(w, r, cfg)
+			deletePathID(urlPath, r.Method, deleteFn, get, etag, cfg)(w, r)
+		case http.MethodOptions:
+			handleOptions(w, allowed, cfg)
 		}
 	}
 
+	if cfg.idempotency != nil {
+		handler = cfg.idempotency.handler(handler, []string{http.MethodPost, http.MethodPatch}, cfg)
+	}
+
+	if cfg.maxBodySize > 0 {
+		handler = limitBody(handler, cfg.maxBodySize)
+	}
+
 	for i := len(cfg.middlewares) - 1; i >= 0; i-- {
 		// we wrap the handler in the middlewares
 		handler = cfg.middlewares[i](handler)
@@ -122,41 +251,20 @@ func resID(requestPath, prefixPath string) string {
 // decode use the content type to decode the data from r into t.
 func decode[T any](r io.Reader, contentType string, cfg entityRouteConfig) (T, error) {
 	var t T
-	decoder, err := encoding.ContentTypeDecoder(r, contentType, cfg.codecs)
+	decoder, err := contentTypeDecoder(r, contentType, cfg)
 	if err != nil {
 		return t, err
 	}
 
 	err = decoder.Decode(&t)
-	return t, err
-}
-
-// TODO: is it used? Delete?
-func updateFieldInEntity[Ent any](entity Ent, fieldName string, fieldValue any) (err error) {
-	defer func() {
-		rerr := recover()
-		if rerr != nil {
-			// TODO(the): add a real full error with error source, etc
-			err = Error{
-				Source: ErrorSource{
-					Pointer: fieldName,
-				},
-				Debug: fmt.Errorf("%v: %w", rerr, err).Error(),
-			}
-		}
-	}()
-	val := reflect.ValueOf(entity)
-	// it should be a pointer type
-	val = val.Elem()
-	field := val.FieldByNameFunc(func(s string) bool {
-		return strings.ToLower(s) == strings.ToLower(fieldName)
-	})
-	field.Set(reflect.ValueOf(fieldValue))
+	if err != nil {
+		return t, decodeError(err, r)
+	}
 
-	return nil
+	return t, nil
 }
 
-func updatePathID[Ent any](urlPath, method string, f updateFunc[Ent], get getFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
+func updatePathID[Ent any](urlPath, method string, f updateFunc[Ent], get getFunc[Ent], create createFunc[Ent], etag etagFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		//TODO add edit mode on
 		id, field, _, contentType, accept, _, err := getIDAndEditMode(w, r, method, urlPath, cfg)
@@ -165,6 +273,12 @@ func updatePathID[Ent any](urlPath, method string, f updateFunc[Ent], get getFun
 			return
 		}
 
+		err = checkIfMatch(r, id, get, etag, cfg)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
 		var ent Ent
 		if field == "" {
 			// if we have no field selected, we just decode the entire entity
@@ -174,64 +288,17 @@ func updatePathID[Ent any](urlPath, method string, f updateFunc[Ent], get getFun
 				return
 			}
 		} else {
-			err = func() (err error) {
-				defer func() {
-					rerr := recover()
-					if rerr != nil {
-						err = Error{
-							Source: ErrorSource{
-								Pointer: field,
-							},
-							Debug: fmt.Errorf("%v: %w", rerr, err).Error(),
-						}
-					}
-				}()
-
-				ent, err = get(r.Context(), id)
-				if err != nil {
-					writeError(w, accept, fmt.Errorf("can not get original entity: %w", err), cfg)
-					return err
-				}
-
-				st := structOf(ent)
-
-				fieldValue := st.FieldByNameFunc(func(s string) bool {
-					return strings.ToLower(s) == strings.ToLower(field)
-				})
-
-				if !fieldValue.CanSet() {
-					fieldValue = fieldValue.Elem()
-					log.Println("fvalue can set: false")
-					if fieldValue.CanSet() {
-						log.Println("fvalue can set: true again")
-					}
-				}
-
-				var fieldData any
-
-				decoder, err := encoding.ContentTypeDecoder(r.Body, contentType, cfg.codecs)
-				if err != nil {
-					return err
-				}
-
-				err = decoder.Decode(&fieldData)
-				if err != nil {
-					writeError(w, accept, fmt.Errorf("can not decode entity field: %w", err), cfg)
-					return err
-				}
-				fieldDataValue := reflect.ValueOf(fieldData)
-
-				if fieldValue.CanSet() {
-					fieldValue.Set(fieldDataValue)
-				} else {
-					writeError(w, accept, fmt.Errorf("can not set entity field: %s", fieldValue.String()), cfg)
-				}
+			// to update a field, we get the entity first, then we decode the field in it,
+			// and we can update the whole entity
+			ent, err = get(r.Context(), id)
+			if err != nil {
+				writeError(w, accept, fmt.Errorf("can not get original entity: %w", err), cfg)
+				return
+			}
 
-				// We've updated the field. We're good to go.
-				return nil
-			}()
+			err = decodeField(&ent, field, r.Body, contentType, cfg)
 			if err != nil {
-				writeError(w, accept, fmt.Errorf("can not decode entity field: %w", err), cfg)
+				writeError(w, accept, err, cfg)
 				return
 			}
 		}
@@ -248,13 +315,53 @@ func updatePathID[Ent any](urlPath, method string, f updateFunc[Ent], get getFun
 			ripreflect.SetID(&ent, id)
 		}
 
-		// To update a field, we need to get the entity first, then reflect on it to get the field and change it
-		// then we can update the whole entity with updateFunc
-		err = f(r.Context(), ent)
+		status := http.StatusOK
+		if field == "" && cfg.upsert {
+			// with upsert, a PUT on an unknown entity creates it
+			_, err = get(r.Context(), id)
+			switch {
+			case isNotFound(err):
+				status = http.StatusCreated
+			case err != nil:
+				writeError(w, accept, fmt.Errorf("can not get original entity: %w", err), cfg)
+				return
+			}
+		}
+
+		if status == http.StatusCreated {
+			// the entity is created where the client wants it to be.
+			err = ripreflect.SetID(&ent, id)
+			if err != nil {
+				writeError(w, accept, err, cfg)
+				return
+			}
+
+			ent, err = create(r.Context(), ent)
+			if err != nil {
+				writeError(w, accept, fmt.Errorf("entity provider create: %w", err), cfg)
+				return
+			}
+
+			location, ok := entityLocation(urlPath, ent)
+			if ok {
+				w.Header().Set("Location", location)
+			}
+		} else {
+			// To update a field, we need to get the entity first, then reflect on it to get the field and change it
+			// then we can update the whole entity with updateFunc
+			ent, err = f(r.Context(), ent)
+			if err != nil {
+				writeError(w, accept, err, cfg)
+				return
+			}
+		}
+
+		err = setETag(w, r, accept, ent, etag)
 		if err != nil {
 			writeError(w, accept, err, cfg)
 			return
 		}
+		setLastModified(w, ent)
 
 		if field != "" {
 			w.WriteHeader(http.StatusNoContent)
@@ -266,7 +373,9 @@ func updatePathID[Ent any](urlPath, method string, f updateFunc[Ent], get getFun
 			Request:        r,
 		}
 
-		err = encoding.AcceptEncoder(rrw, accept, encoding.EditOff, cfg.codecs).Encode(ent)
+		w.WriteHeader(status)
+
+		err = acceptEncoder(rrw, accept, encoding.EditOff, cfg).Encode(ent)
 		if err != nil {
 			writeError(w, accept, err, cfg)
 			return
@@ -274,7 +383,7 @@ func updatePathID[Ent any](urlPath, method string, f updateFunc[Ent], get getFun
 	}
 }
 
-func deletePathID(urlPath, method string, f deleteFunc, cfg entityRouteConfig) http.HandlerFunc {
+func deletePathID[Ent any](urlPath, method string, f deleteFunc, get getFunc[Ent], etag etagFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		cleanedPath, accept, _, err := preprocessRequest(r.Method, method, r.Header, r.URL.Path, cfg)
 		if err != nil {
@@ -288,6 +397,12 @@ func deletePathID(urlPath, method string, f deleteFunc, cfg entityRouteConfig) h
 			return
 		}
 
+		err = checkIfMatch(r, rID, get, etag, cfg)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
 		// we don't need the returning entity, it's mostly a no-op
 		err = f(r.Context(), rID)
 		if err != nil {
@@ -318,20 +433,23 @@ func deletePathID(urlPath, method string, f deleteFunc, cfg entityRouteConfig) h
 	}
 }
 
+// getEntityField splits the request path into the entity id and the path of a field in the entity,
+// e.g.: 1 and address/city for /users/1/address/city
 func getEntityField(entityPrefix, requestPath string) (id, field string) {
 	requestPath = strings.TrimPrefix(requestPath, entityPrefix)
 
-	requestPath = strings.TrimRight(requestPath, "/")
-	requestPath = strings.TrimLeft(requestPath, "/")
+	var segments []string
+	for _, s := range strings.Split(requestPath, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
 
-	if strings.Contains(requestPath, "/") {
-		id, field = path.Split(requestPath)
-		id = strings.TrimRight(id, "/")
-	} else {
-		id = strings.TrimRight(requestPath, "/")
+	if len(segments) == 0 {
+		return "", ""
 	}
 
-	return id, field
+	return segments[0], strings.Join(segments[1:], "/")
 }
 
 func getIDAndEditMode(w http.ResponseWriter, r *http.Request, method string, urlPath string, cfg entityRouteConfig) (id, field, cleanedPath, contentType, accept string, editMode encoding.EditMode, err error) {
@@ -356,47 +474,7 @@ func getIDAndEditMode(w http.ResponseWriter, r *http.Request, method string, url
 	return id, field, cleanedPath, contentType, accept, editMode, nil
 }
 
-func structOf(v any) reflect.Value {
-	val := reflect.ValueOf(v)
-	if val.Kind() == reflect.Pointer {
-		val = val.Elem()
-	}
-	return val
-}
-
-func fieldValue(st reflect.Value, field string) any {
-	val := st.FieldByNameFunc(func(f string) bool {
-		if strings.ToLower(f) != strings.ToLower(field) {
-			return false
-		}
-		return true
-	})
-
-	switch val.Kind() {
-	case reflect.Bool:
-		return val.Bool()
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return val.Int()
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return val.Uint()
-	case reflect.Float32, reflect.Float64:
-		return val.Float()
-	case reflect.Complex64, reflect.Complex128:
-		return val.Complex()
-	case reflect.String:
-		return val.String()
-	case reflect.Slice,
-		reflect.Array,
-		reflect.Map,
-		reflect.Struct,
-		reflect.Chan:
-		return val.Interface()
-	default:
-		return val.String()
-	}
-}
-
-func handleGet[Ent any](urlPath, method string, f getFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
+func handleGet[Ent any](urlPath, method string, f getFunc[Ent], etag etagFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id, field, _, _, accept, editMode, err := getIDAndEditMode(w, r, method, urlPath, cfg)
 		if err != nil {
@@ -404,6 +482,18 @@ func handleGet[Ent any](urlPath, method string, f getFunc[Ent], cfg entityRouteC
 			return
 		}
 
+		fields, err := parseSparseFields[Ent](r.URL.Query())
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		incs, err := parseIncludes[Ent](r.URL.Query(), cfg.references)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
 		res, err := f(r.Context(), id)
 		if err != nil {
 			writeError(w, accept, err, cfg)
@@ -411,16 +501,42 @@ func handleGet[Ent any](urlPath, method string, f getFunc[Ent], cfg entityRouteC
 		}
 
 		var ret any = res
+		var shape encoding.Shape
 		if field != "" {
-			st := structOf(res)
-			ret = fieldValue(st, field)
+			fieldValue, err := entityField(reflect.ValueOf(&res).Elem(), field, false)
+			if err != nil {
+				writeError(w, accept, err, cfg)
+				return
+			}
+			ret = fieldValue.Interface()
+		} else {
+			err = setETag(w, r, accept, res, etag)
+			if err != nil {
+				writeError(w, accept, err, cfg)
+				return
+			}
+			setLastModified(w, res)
+
+			if writeNotModified(w, r) {
+				return
+			}
+
+			// the edit form needs all the fields, to update the whole entity
+			if editMode == encoding.EditOff {
+				shape, err = incs.shape(r.Context(), fields.shape(), []Ent{res})
+				if err != nil {
+					writeError(w, accept, err, cfg)
+					return
+				}
+			}
 		}
+
 		rrw := encoding.RequestResponseWriter{
 			ResponseWriter: w,
 			Request:        r,
 		}
 
-		err = encoding.AcceptEncoder(rrw, accept, editMode, cfg.codecs).Encode(ret)
+		err = acceptEncoder(rrw, accept, editMode, cfg).EncodeShaped(ret, shape)
 		if err != nil {
 			writeError(w, accept, err, cfg)
 			return
@@ -428,7 +544,70 @@ func handleGet[Ent any](urlPath, method string, f getFunc[Ent], cfg entityRouteC
 	}
 }
 
-func handleListAll[Ent any](urlPath, method string, f listFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
+// listQuery lists the entities with the filter and the sort of the query, if any.
+// The other query parameters are field filters only if the provider implements [FilterLister],
+// otherwise they are ignored.
+// It fails if the provider doesn't implement [SortLister] to sort the entities.
+func (l entityListers[Ent]) listQuery(ctx context.Context, query url.Values, offset, limit int) ([]Ent, error) {
+	filter, err := l.parseFilter(query)
+	if err != nil {
+		return nil, err
+	}
+
+	sort, err := parseSort[Ent](query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sort) > 0 && l.sorted == nil {
+		return nil, Error{
+			Status: http.StatusBadRequest,
+			Detail: "this route can not sort its entities",
+			Source: ErrorSource{
+				Parameter: "sort",
+			},
+		}
+	}
+
+	switch {
+	case len(sort) > 0:
+		return l.sorted(ctx, filter, sort, offset, limit)
+	case len(filter) > 0:
+		return l.filtered(ctx, filter, offset, limit)
+	default:
+		return l.list(ctx, offset, limit)
+	}
+}
+
+// parseFilter parses the field filters of the query if the provider can filter its entities.
+func (l entityListers[Ent]) parseFilter(query url.Values) (Filter, error) {
+	if l.filtered == nil {
+		return nil, nil
+	}
+
+	return parseFilter[Ent](query)
+}
+
+// countQuery counts the entities matching the filter of the query, if the provider implements [Counter].
+func (l entityListers[Ent]) countQuery(ctx context.Context, query url.Values) (count int, ok bool, err error) {
+	if l.count == nil {
+		return 0, false, nil
+	}
+
+	filter, err := l.parseFilter(query)
+	if err != nil {
+		return 0, false, err
+	}
+
+	count, err = l.count(ctx, filter)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return count, true, nil
+}
+
+func handleListAll[Ent any](urlPath, method string, l entityListers[Ent], cfg entityRouteConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		_, accept, _, err := preprocessRequest(r.Method, method, r.Header, r.URL.Path, cfg)
 		if err != nil {
@@ -468,10 +647,11 @@ func handleListAll[Ent any](urlPath, method string, f listFunc[Ent], cfg entityR
 			pageSize = int(pageSizeUint)
 		}
 
+		page := 1
 		offset := 0
 		if r.URL.Query().Has("page") {
 			pageStr := r.URL.Query().Get("page")
-			page, err := strconv.ParseUint(pageStr, 10, 64)
+			pageUint, err := strconv.ParseUint(pageStr, 10, 64)
 			if err != nil {
 				err := Error{
 					Status: http.StatusBadRequest,
@@ -484,23 +664,74 @@ func handleListAll[Ent any](urlPath, method string, f listFunc[Ent], cfg entityR
 				return
 			}
 
-			// we switch to 0 index (page 1 = page 0)
-			if page > 0 {
-				page--
-			}
+			// page 0 is the first page too
+			page = max(int(pageUint), 1)
 
-			offset = int(page) * pageSize
+			// we switch to 0 index (page 1 = page 0)
+			offset = (page - 1) * pageSize
 		}
 
 		limit := pageSize
 
-		ents, err := f(r.Context(), offset, limit)
+		fields, err := parseSparseFields[Ent](r.URL.Query())
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		incs, err := parseIncludes[Ent](r.URL.Query(), cfg.references)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		var ents []Ent
+		var links []link
+		isCursor := r.URL.Query().Has("cursor")
+		if isCursor {
+			ents, links, err = l.listCursor(r, limit)
+		} else {
+			ents, err = l.listQuery(r.Context(), r.URL.Query(), offset, limit)
+		}
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		total, hasTotal, err := l.countQuery(r.Context(), r.URL.Query())
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		if hasTotal {
+			w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		}
+
+		if !isCursor {
+			links = pageLinks(r, page, pageSize, len(ents), total, hasTotal)
+		}
+		setLinkHeader(w, links)
+
+		// We don't send Last-Modified for lists: the latest update date of the listed
+		// entities doesn't change when one of them is deleted, the ETag does.
+		err = setETag(w, r, accept, ents, hashETag)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		if writeNotModified(w, r) {
+			return
+		}
+
+		shape, err := incs.shape(r.Context(), fields.shape(), ents)
 		if err != nil {
 			writeError(w, accept, err, cfg)
 			return
 		}
 
-		err = encoding.AcceptEncoder(w, accept, encoding.EditOff, cfg.codecs).Encode(ents)
+		err = acceptEncoder(w, accept, encoding.EditOff, cfg).EncodeShaped(ents, shape)
 		if err != nil {
 			writeError(w, accept, err, cfg)
 			return
@@ -508,7 +739,7 @@ func handleListAll[Ent any](urlPath, method string, f listFunc[Ent], cfg entityR
 	}
 }
 
-func handleCreate[Ent any](method, urlPath string, f createFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
+func handleCreate[Ent any](method, urlPath string, f createFunc[Ent], etag etagFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		accept, err := contentNegociateBestHeaderValue(r.Header, "Accept", cfg.codecs.OrderedMimeTypes)
 		if err != nil {
@@ -517,7 +748,7 @@ func handleCreate[Ent any](method, urlPath string, f createFunc[Ent], cfg entity
 		}
 
 		if r.Method != method {
-			writeError(w, accept, fmt.Errorf("method not allowed: %s", r.Method), cfg)
+			badMethodHandler(w, r, []string{method}, cfg)
 			return
 		}
 
@@ -533,15 +764,46 @@ func handleCreate[Ent any](method, urlPath string, f createFunc[Ent], cfg entity
 			return
 		}
 
+		// the client can not choose the id of the entity if it is read-only
+		if hasReadOnlyID[Ent]() {
+			id, _, err := ripreflect.FindEntityID(&res)
+			if err != nil {
+				writeError(w, accept, err, cfg)
+				return
+			}
+			id.SetZero()
+		}
+
 		res, err = f(r.Context(), res)
 		if err != nil {
 			writeError(w, accept, fmt.Errorf("entity provider create: %w", err), cfg)
 			return
 		}
 
+		err = setETag(w, r, accept, res, etag)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+		setLastModified(w, res)
+
+		location, ok := entityLocation(urlPath, res)
+		if ok {
+			w.Header().Set("Location", location)
+		}
+
+		preference, applied := returnPreference(r, cfg)
+		if applied {
+			w.Header().Set("Preference-Applied", "return="+string(preference))
+		}
+
 		w.WriteHeader(http.StatusCreated)
 
-		err = encoding.AcceptEncoder(w, accept, encoding.EditOff, cfg.codecs).Encode(res)
+		if preference == ReturnMinimal {
+			return
+		}
+
+		err = acceptEncoder(w, accept, encoding.EditOff, cfg).Encode(res)
 		if err != nil {
 			writeError(w, accept, fmt.Errorf("encode POST body: %w", err), cfg)
 			return
@@ -549,6 +811,16 @@ func handleCreate[Ent any](method, urlPath string, f createFunc[Ent], cfg entity
 	}
 }
 
+// entityLocation is the URL path of ent in the entity collection at urlPath.
+func entityLocation(urlPath string, ent any) (string, bool) {
+	id, err := ripreflect.GetID(ent)
+	if err != nil || id == "" {
+		return "", false
+	}
+
+	return path.Join(urlPath, url.PathEscape(id)), true
+}
+
 // start Handle OMIT
 
 // Handle is a generic HTTP handler that maps an HTTP method to a InputOutputFunc f.
@@ -567,7 +839,10 @@ func Handle[
 
 	cfg = setEntityRouteConfigDefaults(cfg)
 
-	return func(w http.ResponseWriter, r *http.Request) {
+	validate := newValidator[Input]()
+	readOnly := readOnlyFields[Input]()
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
 		accept, err := contentNegociateBestHeaderValue(r.Header, "Accept", cfg.codecs.OrderedMimeTypes)
 		if err != nil {
 			writeError(w, accept, fmt.Errorf("bad accept header format: %w", err), cfg)
@@ -575,19 +850,16 @@ func Handle[
 		}
 
 		if r.Method != method {
-			writeError(w, accept, fmt.Errorf("method not allowed: %s", r.Method), cfg)
+			badMethodHandler(w, r, []string{method}, cfg)
 			return
 		}
 
-		contentType, err := contentNegociateBestHeaderValue(r.Header, "Content-Type", cfg.codecs.OrderedMimeTypes)
-		if err != nil {
-			writeError(w, accept, fmt.Errorf("bad content type header format: %w", err), cfg)
-			return
-		}
+		r, cancel := withTimeout(r, methodOperation(method), cfg)
+		defer cancel()
 
-		req, err := decode[Input](r.Body, contentType, cfg)
+		req, err := decodeInput(r, readOnly, validate, cfg)
 		if err != nil {
-			writeError(w, accept, fmt.Errorf("decode %s body: %w", r.Method, err), cfg)
+			writeError(w, accept, err, cfg)
 			return
 		}
 
@@ -597,22 +869,43 @@ func Handle[
 			return
 		}
 
-		err = encoding.AcceptEncoder(w, accept, encoding.EditOff, cfg.codecs).Encode(res)
+		err = acceptEncoder(w, accept, encoding.EditOff, cfg).Encode(res)
 		if err != nil {
 			writeError(w, accept, fmt.Errorf("encode %s body: %w", r.Method, err), cfg)
 			return
 		}
 	}
+
+	if cfg.idempotency != nil {
+		handler = cfg.idempotency.handler(handler, []string{method}, cfg)
+	}
+
+	if cfg.maxBodySize > 0 {
+		handler = limitBody(handler, cfg.maxBodySize)
+	}
+
+	return handler
 }
 
-func badMethodHandler(w http.ResponseWriter, r *http.Request, cfg entityRouteConfig) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		accept, err := contentNegociateBestHeaderValue(r.Header, "Accept", cfg.codecs.OrderedMimeTypes)
-		if err != nil {
-			writeError(w, accept, fmt.Errorf("bad accept header format: %w", err), cfg)
-			return
-		}
+// decodeInput decodes the input of a [Handle] function from the request body, and validates it.
+func decodeInput[Input any](r *http.Request, readOnly []int, validate validateFunc[Input], cfg entityRouteConfig) (Input, error) {
+	var req Input
+	contentType, err := contentNegociateBestHeaderValue(r.Header, "Content-Type", cfg.codecs.OrderedMimeTypes)
+	if err != nil {
+		return req, fmt.Errorf("bad content type header format: %w", err)
+	}
+
+	req, err = decode[Input](r.Body, contentType, cfg)
+	if err != nil {
+		return req, fmt.Errorf("decode %s body: %w", r.Method, err)
+	}
+
+	clearReadOnly(&req, readOnly)
 
-		writeError(w, accept, Error{Status: http.StatusMethodNotAllowed, Detail: "bad method"}, cfg)
+	err = validate(req)
+	if err != nil {
+		return req, err
 	}
+
+	return req, nil
 }
@@ -1,6 +1,17 @@
 package rip
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
 
 func TestGetEntityField(t *testing.T) {
 	cases := []struct {
@@ -20,6 +31,8 @@ func TestGetEntityField(t *testing.T) {
 		{"/ent/1///", "/ent", "1", ""},
 		{"/ent/1////name", "/ent", "1", "name"},
 		{"/ent///1////name//", "/ent", "1", "name"},
+		{"/ent/1/address/city", "/ent", "1", "address/city"},
+		{"/ent/1/tags/0/", "/ent", "1", "tags/0"},
 	}
 
 	for _, c := range cases {
@@ -35,3 +48,107 @@ func TestGetEntityField(t *testing.T) {
 		})
 	}
 }
+
+type address struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type customer struct {
+	ID        string    `json:"id"`
+	Age       int       `json:"age"`
+	BirthDate time.Time `json:"birth_date"`
+	Address   *address  `json:"address"`
+	Tags      []string  `json:"tags"`
+}
+
+func TestUpdateEntityField(t *testing.T) {
+	cp := newMemoryProvider(customer{ID: "1", Tags: []string{"a", "b"}})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/customers/", cp, WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	put := func(t *testing.T, path, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, s.URL+path, strings.NewReader(body))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		return resp
+	}
+
+	birthDate := time.Date(2000, time.January, 2, 0, 0, 0, 0, time.UTC)
+	want := customer{
+		ID:        "1",
+		Age:       42,
+		BirthDate: birthDate,
+		Address:   &address{City: "Paris"},
+		Tags:      []string{"a", "c"},
+	}
+
+	for path, body := range map[string]string{
+		"/customers/1/age":          `42`,
+		"/customers/1/birth_date":   `"2000-01-02T00:00:00Z"`,
+		"/customers/1/address/city": `"Paris"`,
+		"/customers/1/tags/1":       `"c"`,
+	} {
+		resp := put(t, path, body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatalf("PUT %s status code is not 204: %d", path, resp.StatusCode)
+		}
+	}
+
+	got, err := cp.Get(context.Background(), "1")
+	panicErr(t, err)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("entity is not\n%+v:\n%+v", want, got)
+	}
+
+	t.Run("get nested field", func(t *testing.T) {
+		resp, err := s.Client().Get(s.URL + "/customers/1/address/city")
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		var city string
+		err = json.NewDecoder(resp.Body).Decode(&city)
+		panicErr(t, err)
+		if city != "Paris" {
+			t.Fatal("city is not Paris:", city)
+		}
+	})
+
+	errCases := map[string]struct {
+		path    string
+		body    string
+		status  int
+		pointer string
+	}{
+		"unknown field":        {path: "/customers/1/name", body: `"Joe"`, status: http.StatusNotFound, pointer: "/name"},
+		"unknown nested field": {path: "/customers/1/address/zip", body: `"75000"`, status: http.StatusNotFound, pointer: "/address/zip"},
+		"index out of range":   {path: "/customers/1/tags/5", body: `"d"`, status: http.StatusNotFound, pointer: "/tags/5"},
+		"bad value type":       {path: "/customers/1/age", body: `"old"`, status: http.StatusBadRequest, pointer: "/age"},
+	}
+
+	for name, c := range errCases {
+		t.Run(name, func(t *testing.T) {
+			resp := put(t, c.path, c.body)
+			defer resp.Body.Close()
+			if resp.StatusCode != c.status {
+				t.Fatalf("status code is not %d: %d", c.status, resp.StatusCode)
+			}
+
+			var e Error
+			err := json.NewDecoder(resp.Body).Decode(&e)
+			panicErr(t, err)
+			if e.Source.Pointer != c.pointer {
+				t.Fatalf("error pointer is not %q: %q", c.pointer, e.Source.Pointer)
+			}
+		})
+	}
+}
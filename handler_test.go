@@ -326,6 +326,52 @@ func TestMiddleware(t *testing.T) {
 	}
 }
 
+func TestUpsert(t *testing.T) {
+	up := newUserProvider()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/users/", up, WithCodecs(json.Codec), WithUpsert()))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	put := func(t *testing.T, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPut, s.URL+"/users/Jane", bytes.NewBufferString(body))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("create", func(t *testing.T) {
+		resp := put(t, `{"name": "Joe", "email_address": "jane@example.com"}`)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatal("put status code is not 201:", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Location"); got != "/users/Jane" {
+			t.Fatal("bad Location header:", got)
+		}
+
+		u, ok := up.mem["Jane"]
+		if !ok || u.EmailAddress != "jane@example.com" {
+			t.Fatal("user not created with the path ID:", up.mem)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		resp := put(t, `{"email_address": "jane@example.org"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("put status code is not 200:", resp.StatusCode)
+		}
+		if up.mem["Jane"].EmailAddress != "jane@example.org" {
+			t.Fatal("user not updated:", up.mem["Jane"])
+		}
+	})
+}
+
 func panicErr(t *testing.T, err error) {
 	t.Helper()
 	if err != nil {
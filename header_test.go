@@ -2,8 +2,10 @@ package rip
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/dolanor/rip/encoding"
 	"github.com/dolanor/rip/encoding/json"
 	"github.com/dolanor/rip/encoding/xml"
 )
@@ -19,6 +21,17 @@ func TestChooseHeaderValue(t *testing.T) {
 		"2x2 values with q":           {map[string][]string{"a": {"text/xml; q=0.7, application/json; q=0.1", "application/json; q=0.3, text/plaintext;q=0.71"}}, "text/xml"},
 		"2x2 values with q and other": {map[string][]string{"a": {"text/xml; nope; q=0.7, application/json; q=0.1", "application/json; q=0.3, text/plaintext; other;q=0.71"}}, "text/xml"},
 		"nothing":                     {map[string][]string{"a": {""}}, ""},
+		"no header":                   {map[string][]string{}, "application/json"},
+		"parameters":                  {map[string][]string{"a": {"application/json; charset=utf-8"}}, "application/json"},
+		"quoted parameter":            {map[string][]string{"a": {`text/xml; profile="a,b"; q=0.5, application/json; q=0.4`}}, "text/xml"},
+		"q whitespace":                {map[string][]string{"a": {"text/xml ; q = 0.2 , application/json;q=0.9 "}}, "application/json"},
+		"type wildcard":               {map[string][]string{"a": {"text/*"}}, "text/xml"},
+		"wildcard last":               {map[string][]string{"a": {"text/html, */*; q=0.1"}}, "application/json"},
+		"wildcard with content type":  {map[string][]string{"a": {"*/*"}, "Content-Type": {"text/xml; charset=utf-8"}}, "text/xml"},
+		"server order":                {map[string][]string{"a": {"application/*, text/*"}}, "application/json"},
+		"specificity":                 {map[string][]string{"a": {"application/*; q=0.5, application/json; q=0.1, text/xml; q=0.3"}}, "application/xml"},
+		"excluded":                    {map[string][]string{"a": {"*/*, application/json; q=0"}}, "application/xml"},
+		"all excluded":                {map[string][]string{"a": {"application/*; q=0, text/xml;q=0"}}, ""},
 	}
 
 	codecs, _ := buildCodecOptions(json.Codec, xml.Codec)
@@ -55,3 +68,28 @@ func TestBadHeaderQArgValue(t *testing.T) {
 		}
 	}
 }
+
+func TestContentTypeDecoderParameters(t *testing.T) {
+	codecs, _ := buildCodecOptions(json.Codec, xml.Codec)
+
+	decoder, err := encoding.ContentTypeDecoder(strings.NewReader(`{"id": "1"}`), "application/json; charset=utf-8", codecs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var e struct {
+		ID string `json:"id"`
+	}
+	err = decoder.Decode(&e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.ID != "1" {
+		t.Fatal("bad decoded value:", e)
+	}
+
+	_, err = encoding.ContentTypeDecoder(strings.NewReader(""), "application/*", codecs)
+	if !errors.Is(err, encoding.ErrNoEncoderAvailable) {
+		t.Fatal("a media range is decoded:", err)
+	}
+}
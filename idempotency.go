@@ -0,0 +1,255 @@
+package rip
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the request header with the key identifying a request and its retries,
+// see https://datatracker.ietf.org/doc/draft-ietf-httpapi-idempotency-key-header/
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// defaultIdempotencyTTL is the time the responses are replayed, if [WithIdempotency] has no TTL.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotentResponse is the response to the first request with an idempotency key,
+// replayed for the following requests with the same key.
+type IdempotentResponse struct {
+	// Fingerprint identifies the request: a key reused for another request is rejected.
+	Fingerprint string
+
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// IdempotencyStore stores the responses to the requests with an idempotency key,
+// see [WithIdempotency].
+type IdempotencyStore interface {
+	// Get gets the response stored for key. ok is false if there is none, or if it expired.
+	Get(ctx context.Context, key string) (resp IdempotentResponse, ok bool, err error)
+
+	// Set stores the response for key, for ttl.
+	Set(ctx context.Context, key string, resp IdempotentResponse, ttl time.Duration) error
+}
+
+// MemoryIdempotencyStore is an [IdempotencyStore] keeping the responses in memory.
+// It only works for a single server instance.
+type MemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	responses map[string]memoryIdempotentResponse
+}
+
+type memoryIdempotentResponse struct {
+	resp    IdempotentResponse
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore creates an empty [MemoryIdempotencyStore].
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		responses: map[string]memoryIdempotentResponse{},
+	}
+}
+
+func (s *MemoryIdempotencyStore) Get(ctx context.Context, key string) (IdempotentResponse, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.responses[key]
+	if !ok || !now().Before(stored.expires) {
+		return IdempotentResponse{}, false, nil
+	}
+
+	return stored.resp, true, nil
+}
+
+func (s *MemoryIdempotencyStore) Set(ctx context.Context, key string, resp IdempotentResponse, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := now()
+	for k, stored := range s.responses {
+		// the expired responses are removed, so the store doesn't grow forever
+		if !t.Before(stored.expires) {
+			delete(s.responses, k)
+		}
+	}
+
+	s.responses[key] = memoryIdempotentResponse{
+		resp:    resp,
+		expires: t.Add(ttl),
+	}
+
+	return nil
+}
+
+// idempotency replays the responses to the requests with an idempotency key.
+type idempotency struct {
+	store IdempotencyStore
+	ttl   time.Duration
+
+	// inFlight are the keys of the requests being handled.
+	inFlight sync.Map
+}
+
+// handler makes the requests handled by next idempotent, with the Idempotency-Key request
+// header, for the methods of methods.
+func (id *idempotency) handler(next http.HandlerFunc, methods []string, cfg entityRouteConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || !slices.Contains(methods, r.Method) {
+			next(w, r)
+			return
+		}
+
+		accept, err := contentNegociateBestHeaderValue(r.Header, "Accept", cfg.codecs.OrderedMimeTypes)
+		if err != nil {
+			accept = ""
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, accept, fmt.Errorf("read request body: %w", err), cfg)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		fingerprint := requestFingerprint(r, body)
+
+		if id.replayStored(w, r, key, fingerprint, accept, cfg) {
+			return
+		}
+
+		_, loaded := id.inFlight.LoadOrStore(key, struct{}{})
+		if loaded {
+			writeError(w, accept, Error{
+				Status: http.StatusConflict,
+				Detail: "a request with this idempotency key is in progress",
+				Source: ErrorSource{
+					Header: IdempotencyKeyHeader,
+				},
+			}, cfg)
+			return
+		}
+		defer id.inFlight.Delete(key)
+
+		// the first request can have been handled between the store check and the in-flight one
+		if id.replayStored(w, r, key, fingerprint, accept, cfg) {
+			return
+		}
+
+		rec := &recordingResponseWriter{ResponseWriter: w}
+		next(rec, r)
+
+		if rec.status == 0 {
+			// nothing was written, net/http answers 200 OK
+			rec.status = http.StatusOK
+			rec.header = w.Header().Clone()
+		}
+
+		if rec.status >= http.StatusInternalServerError {
+			// the request can be retried, it may succeed
+			return
+		}
+
+		err = id.store.Set(r.Context(), key, IdempotentResponse{
+			Fingerprint: fingerprint,
+			Status:      rec.status,
+			Header:      rec.header,
+			Body:        rec.body.Bytes(),
+		}, id.ttl)
+		if err != nil {
+			logger := cfg.logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+			logger.Error("store idempotent response", "key", key, "error", err)
+		}
+	}
+}
+
+// replayStored replays the response stored for key, or rejects the request if key is used
+// for another request. done is false if there is no stored response: the request is handled.
+func (id *idempotency) replayStored(w http.ResponseWriter, r *http.Request, key, fingerprint, accept string, cfg entityRouteConfig) (done bool) {
+	stored, ok, err := id.store.Get(r.Context(), key)
+	if err != nil {
+		writeError(w, accept, fmt.Errorf("get idempotent response: %w", err), cfg)
+		return true
+	}
+
+	if !ok {
+		return false
+	}
+
+	if stored.Fingerprint != fingerprint {
+		writeError(w, accept, Error{
+			Status: http.StatusUnprocessableEntity,
+			Detail: "the idempotency key is already used for another request",
+			Source: ErrorSource{
+				Header: IdempotencyKeyHeader,
+			},
+		}, cfg)
+		return true
+	}
+
+	replay(w, stored)
+	return true
+}
+
+// requestFingerprint identifies a request by its method, its path and its body.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", r.Method, r.URL.RequestURI())
+	h.Write(body)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes the stored response again.
+func replay(w http.ResponseWriter, resp IdempotentResponse) {
+	for k, v := range resp.Header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// recordingResponseWriter records the response it writes.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (w *recordingResponseWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+
+	w.status = status
+	w.header = w.ResponseWriter.Header().Clone()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
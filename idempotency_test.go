@@ -0,0 +1,212 @@
+package rip
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+type payment struct {
+	ID     string `json:"id"`
+	Amount int    `json:"amount"`
+}
+
+// createCountMemoryProvider counts the calls to Create.
+type createCountMemoryProvider[Ent any] struct {
+	*memoryProvider[Ent]
+
+	creates *atomic.Int32
+}
+
+func (cp createCountMemoryProvider[Ent]) Create(ctx context.Context, ent Ent) (Ent, error) {
+	cp.creates.Add(1)
+	return cp.memoryProvider.Create(ctx, ent)
+}
+
+// staleIdempotencyStore misses the stored response on the first Get after missNext is set,
+// like a retry checking the store just before the first request stored its response.
+type staleIdempotencyStore struct {
+	*MemoryIdempotencyStore
+
+	missNext *atomic.Bool
+}
+
+func (s staleIdempotencyStore) Get(ctx context.Context, key string) (IdempotentResponse, bool, error) {
+	if s.missNext.CompareAndSwap(true, false) {
+		return IdempotentResponse{}, false, nil
+	}
+
+	return s.MemoryIdempotencyStore.Get(ctx, key)
+}
+
+func TestIdempotency(t *testing.T) {
+	clock := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	pp := createCountMemoryProvider[payment]{
+		memoryProvider: newMemoryProvider[payment](),
+		creates:        &atomic.Int32{},
+	}
+
+	var handled atomic.Int32
+	charge := func(ctx context.Context, p payment) (payment, error) {
+		if handled.Add(1) == 1 {
+			return p, errors.New("the bank is down")
+		}
+		return p, nil
+	}
+
+	var puts atomic.Int32
+	put := func(ctx context.Context, p payment) (payment, error) {
+		puts.Add(1)
+		return p, nil
+	}
+
+	store := staleIdempotencyStore{
+		MemoryIdempotencyStore: NewMemoryIdempotencyStore(),
+		missNext:               &atomic.Bool{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/payments/", pp, WithCodecs(jsoncodec.Codec), WithIdempotency(store, time.Hour)))
+	mux.HandleFunc("/charge", Handle(http.MethodPost, charge, WithCodecs(jsoncodec.Codec), WithIdempotency(nil, 0)))
+	mux.HandleFunc("/put", Handle(http.MethodPut, put, WithCodecs(jsoncodec.Codec), WithIdempotency(nil, 0)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	do := func(t *testing.T, method, path, key, body string) (*http.Response, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		panicErr(t, err)
+
+		return resp, string(b)
+	}
+
+	post := func(t *testing.T, path, key, body string) (*http.Response, string) {
+		t.Helper()
+		return do(t, http.MethodPost, path, key, body)
+	}
+
+	t.Run("replay", func(t *testing.T) {
+		first, firstBody := post(t, "/payments/", "key-1", `{"id": "1", "amount": 10}`)
+		if first.StatusCode != http.StatusCreated {
+			t.Fatal("status code is not 201:", first.StatusCode)
+		}
+
+		retry, retryBody := post(t, "/payments/", "key-1", `{"id": "1", "amount": 10}`)
+		if retry.StatusCode != http.StatusCreated || retryBody != firstBody {
+			t.Fatal("the response is not replayed:", retry.StatusCode, retryBody)
+		}
+		if retry.Header.Get("Location") != first.Header.Get("Location") || retry.Header.Get("Idempotent-Replayed") != "true" {
+			t.Fatal("bad replayed headers:", retry.Header)
+		}
+
+		if pp.creates.Load() != 1 {
+			t.Fatal("the entity is created again:", pp.creates.Load())
+		}
+	})
+
+	t.Run("stored during the retry", func(t *testing.T) {
+		store.missNext.Store(true)
+
+		retry, _ := post(t, "/payments/", "key-1", `{"id": "1", "amount": 10}`)
+		if retry.StatusCode != http.StatusCreated || retry.Header.Get("Idempotent-Replayed") != "true" {
+			t.Fatal("the response is not replayed:", retry.StatusCode)
+		}
+
+		if pp.creates.Load() != 1 {
+			t.Fatal("the entity is created again:", pp.creates.Load())
+		}
+	})
+
+	t.Run("reused key", func(t *testing.T) {
+		resp, body := post(t, "/payments/", "key-1", `{"id": "2", "amount": 20}`)
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatal("status code is not 422:", resp.StatusCode, body)
+		}
+		if !strings.Contains(body, IdempotencyKeyHeader) {
+			t.Fatal("the error doesn't point to the header:", body)
+		}
+	})
+
+	t.Run("without key", func(t *testing.T) {
+		before := pp.creates.Load()
+		for i := 0; i < 2; i++ {
+			resp, _ := post(t, "/payments/", "", `{"id": "3", "amount": 30}`)
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatal("status code is not 201:", resp.StatusCode)
+			}
+		}
+
+		if pp.creates.Load()-before != 2 {
+			t.Fatal("the requests without key are replayed")
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		clock = clock.Add(2 * time.Hour)
+		before := pp.creates.Load()
+
+		resp, _ := post(t, "/payments/", "key-1", `{"id": "4", "amount": 40}`)
+		if resp.StatusCode != http.StatusCreated || resp.Header.Get("Idempotent-Replayed") != "" {
+			t.Fatal("the expired response is replayed:", resp.StatusCode)
+		}
+		if pp.creates.Load()-before != 1 {
+			t.Fatal("the entity is not created")
+		}
+	})
+
+	t.Run("handle", func(t *testing.T) {
+		resp, _ := post(t, "/charge", "charge-1", `{"id": "1", "amount": 10}`)
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatal("status code is not 500:", resp.StatusCode)
+		}
+
+		// a server error is not stored, the retry can succeed
+		for i := 0; i < 2; i++ {
+			resp, _ = post(t, "/charge", "charge-1", `{"id": "1", "amount": 10}`)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("status code is not 200:", resp.StatusCode)
+			}
+		}
+
+		if handled.Load() != 2 {
+			t.Fatal("bad number of handled requests:", handled.Load())
+		}
+	})
+
+	t.Run("handle method", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			resp, _ := do(t, http.MethodPut, "/put", "put-1", `{"id": "1", "amount": 10}`)
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("status code is not 200:", resp.StatusCode)
+			}
+		}
+
+		if puts.Load() != 1 {
+			t.Fatal("the PUT request is not idempotent:", puts.Load())
+		}
+	})
+}
@@ -0,0 +1,219 @@
+package rip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/dolanor/rip/encoding"
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// ReferencedRoute is a route whose entities can be referenced by the entities of another route,
+// and included in its responses, see [WithReferences].
+type ReferencedRoute interface {
+	Route
+
+	// entityType is the type of the entities of the route.
+	entityType() reflect.Type
+
+	// getEntity gets the entity with id from the route provider.
+	// Its errors are mapped to an [Error] with the route configuration.
+	getEntity(ctx context.Context, id string) (any, error)
+}
+
+// referenceName returns the name of a referenced route, used in the `rip:"ref=…"` struct tags.
+// e.g.: albums for the /api/albums/ route.
+func referenceName(route ReferencedRoute) string {
+	return path.Base(strings.Trim(route.Path(), "/"))
+}
+
+// inclusion is a referenced entity to include in the response.
+type inclusion struct {
+	// name is the name of the relation in the include query parameter, e.g. album.
+	name string
+
+	// refField is the index of the field holding the id of the referenced entity, e.g. AlbumID.
+	refField int
+
+	route ReferencedRoute
+}
+
+// includes are the referenced entities to include in the responses, selected with the
+// include query parameter:
+//
+//	?include=album,artist
+type includes[Ent any] []inclusion
+
+// relationName returns the name of the relation of a reference field, from its json name
+// or its Go name without the id suffix.
+// e.g.: album for AlbumID with the `json:"album_id"` struct tag
+func relationName(f reflect.StructField) string {
+	jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if jsonName != "" && jsonName != "-" {
+		return strings.TrimSuffix(jsonName, "_id")
+	}
+
+	name := strings.TrimSuffix(f.Name, "ID")
+	name = strings.TrimSuffix(name, "Id")
+	return strings.ToLower(name)
+}
+
+// parseIncludes parses the include query parameter.
+// The relations are the fields of Ent with a `rip:"ref=…"` struct tag, whose route is in references.
+func parseIncludes[Ent any](query url.Values, references map[string]ReferencedRoute) (includes[Ent], error) {
+	if !query.Has("include") {
+		return nil, nil
+	}
+
+	var ent Ent
+	structType, ok := ripreflect.StructType(reflect.TypeOf(ent))
+	if !ok || isProtoMessage(structType) {
+		return nil, Error{
+			Status: http.StatusBadRequest,
+			Detail: "this entity can not include other entities",
+			Source: ErrorSource{
+				Parameter: "include",
+			},
+		}
+	}
+
+	relations := map[string]inclusion{}
+	for i := 0; i < structType.NumField(); i++ {
+		f := structType.Field(i)
+		refName, ok := ripreflect.RIPTagValue(f, ripreflect.TagRef)
+		if !f.IsExported() || !ok {
+			continue
+		}
+
+		route, ok := references[refName]
+		if !ok {
+			continue
+		}
+
+		relations[relationName(f)] = inclusion{
+			name:     relationName(f),
+			refField: i,
+			route:    route,
+		}
+	}
+
+	var incs includes[Ent]
+	for _, v := range query["include"] {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			inc, ok := relations[name]
+			if !ok {
+				return nil, Error{
+					Status: http.StatusBadRequest,
+					Detail: fmt.Sprintf("unknown relation %q", name),
+					Source: ErrorSource{
+						Parameter: "include",
+					},
+				}
+			}
+
+			if !slices.ContainsFunc(incs, func(i inclusion) bool { return i.name == name }) {
+				incs = append(incs, inc)
+			}
+		}
+	}
+
+	return incs, nil
+}
+
+// includedEntities gets the referenced entities, caching them by id for the whole response.
+type includedEntities map[string]map[string]reflect.Value
+
+// get gets the entity referenced by the field of inc in ent, as a pointer.
+// It returns an invalid value if the field is empty, or if the referenced entity doesn't exist.
+func (ie includedEntities) get(ctx context.Context, ent reflect.Value, inc inclusion) (reflect.Value, error) {
+	ref := ent.Field(inc.refField)
+	if ref.Kind() == reflect.Pointer {
+		if ref.IsNil() {
+			return reflect.Value{}, nil
+		}
+		ref = ref.Elem()
+	}
+
+	if ref.IsZero() {
+		return reflect.Value{}, nil
+	}
+
+	id := fmt.Sprint(ref.Interface())
+	if v, ok := ie[inc.name][id]; ok {
+		return v, nil
+	}
+
+	res, err := inc.route.getEntity(ctx, id)
+	var e Error
+	isNotFound := errors.As(err, &e) && e.Status == http.StatusNotFound
+	if err != nil && !isNotFound {
+		return reflect.Value{}, fmt.Errorf("include %s %s: %w", inc.name, id, err)
+	}
+
+	var v reflect.Value
+	if err == nil {
+		v = reflect.ValueOf(res)
+		if v.Kind() != reflect.Pointer {
+			p := reflect.New(v.Type())
+			p.Elem().Set(v)
+			v = p
+		}
+	}
+
+	if ie[inc.name] == nil {
+		ie[inc.name] = map[string]reflect.Value{}
+	}
+	ie[inc.name][id] = v
+
+	return v, nil
+}
+
+// shape adds the entities referenced by ents to shape: the included entities are added to the
+// data of each entity, by relation name.
+func (incs includes[Ent]) shape(ctx context.Context, shape encoding.Shape, ents []Ent) (encoding.Shape, error) {
+	if len(incs) == 0 {
+		return shape, nil
+	}
+
+	cache := includedEntities{}
+	included := make([][]encoding.Field, len(ents))
+	for i, ent := range ents {
+		v := derefValue(reflect.ValueOf(ent))
+		if !v.IsValid() {
+			continue
+		}
+
+		for _, inc := range incs {
+			ref, err := cache.get(ctx, v, inc)
+			if err != nil {
+				return shape, err
+			}
+
+			if ref.IsValid() {
+				included[i] = append(included[i], encoding.Field{Name: inc.name, Value: ref.Interface()})
+			}
+		}
+	}
+
+	shape.Extra = func(i int) []encoding.Field {
+		return included[i]
+	}
+
+	return shape, nil
+}
+
+func derefValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+
+	return v
+}
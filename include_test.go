@@ -0,0 +1,143 @@
+package rip
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dolanor/rip/encoding/html"
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+type track struct {
+	ID      string `json:"id" xml:"id"`
+	Title   string `json:"title" xml:"title"`
+	AlbumID string `json:"album_id" xml:"album_id" rip:"ref=albums"`
+}
+
+func TestInclude(t *testing.T) {
+	albums := NewEntityRoute("/albums/", newMemoryProvider(
+		album{ID: "1", Title: "debut"},
+	), WithCodecs(jsoncodec.Codec))
+
+	tp := newMemoryProvider(
+		track{ID: "1", Title: "intro", AlbumID: "1"},
+		track{ID: "2", Title: "outro", AlbumID: "1"},
+		track{ID: "3", Title: "single"},
+		track{ID: "4", Title: "lost", AlbumID: "42"},
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/tracks/", tp, WithCodecs(jsoncodec.Codec, html.NewEntityCodec("/tracks/")), WithReferences(albums)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	type includedTrack struct {
+		track
+		Album *album `json:"album"`
+	}
+
+	cases := map[string]struct {
+		path      string
+		status    int
+		tracks    []includedTrack
+		parameter string
+	}{
+		"get": {
+			path:   "/tracks/1?include=album",
+			status: http.StatusOK,
+			tracks: []includedTrack{{track{ID: "1", Title: "intro", AlbumID: "1"}, &album{ID: "1", Title: "debut"}}},
+		},
+		"get without reference": {
+			path:   "/tracks/3?include=album",
+			status: http.StatusOK,
+			tracks: []includedTrack{{track{ID: "3", Title: "single"}, nil}},
+		},
+		"get missing reference": {
+			path:   "/tracks/4?include=album",
+			status: http.StatusOK,
+			tracks: []includedTrack{{track{ID: "4", Title: "lost", AlbumID: "42"}, nil}},
+		},
+		"get with fields": {
+			path:   "/tracks/1?include=album&fields=title",
+			status: http.StatusOK,
+			tracks: []includedTrack{{track{ID: "1", Title: "intro"}, &album{ID: "1", Title: "debut"}}},
+		},
+		"list": {
+			path:   "/tracks/?include=album&page_size=2",
+			status: http.StatusOK,
+			tracks: []includedTrack{
+				{track{ID: "1", Title: "intro", AlbumID: "1"}, &album{ID: "1", Title: "debut"}},
+				{track{ID: "2", Title: "outro", AlbumID: "1"}, &album{ID: "1", Title: "debut"}},
+			},
+		},
+		"unknown relation": {
+			path:      "/tracks/1?include=artist",
+			status:    http.StatusBadRequest,
+			parameter: "include",
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := s.Client().Get(s.URL + c.path)
+			panicErr(t, err)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != c.status {
+				t.Fatalf("status code is not %d: %d", c.status, resp.StatusCode)
+			}
+
+			if c.status != http.StatusOK {
+				var e Error
+				err = json.NewDecoder(resp.Body).Decode(&e)
+				panicErr(t, err)
+				if e.Source.Parameter != c.parameter {
+					t.Fatalf("error parameter is not %q: %q", c.parameter, e.Source.Parameter)
+				}
+				return
+			}
+
+			var tracks []includedTrack
+			if strings.HasPrefix(c.path, "/tracks/?") {
+				err = json.NewDecoder(resp.Body).Decode(&tracks)
+			} else {
+				tracks = make([]includedTrack, 1)
+				err = json.NewDecoder(resp.Body).Decode(&tracks[0])
+			}
+			panicErr(t, err)
+
+			if len(tracks) != len(c.tracks) {
+				t.Fatalf("tracks are not %v: %v", c.tracks, tracks)
+			}
+			for i := range tracks {
+				if tracks[i].track != c.tracks[i].track {
+					t.Fatalf("track is not %v: %v", c.tracks[i].track, tracks[i].track)
+				}
+				if (tracks[i].Album == nil) != (c.tracks[i].Album == nil) ||
+					tracks[i].Album != nil && *tracks[i].Album != *c.tracks[i].Album {
+					t.Fatalf("included album is not %v: %v", c.tracks[i].Album, tracks[i].Album)
+				}
+			}
+		})
+	}
+
+	t.Run("html", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, s.URL+"/tracks/1?include=album", nil)
+		panicErr(t, err)
+		req.Header.Set("Accept", "text/html")
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		panicErr(t, err)
+		if !strings.Contains(string(b), "debut") {
+			t.Fatal("no included album in the HTML:", string(b))
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"time"
 )
 
 const MissingIDField = "<MISSING ID FIELD>"
@@ -64,14 +65,151 @@ func FieldIDName(entity any) string {
 }
 
 func HasRIPIDField(f reflect.StructField) bool {
+	return HasRIPTag(f, TagID)
+}
+
+const (
+	// TagID marks the ID field of an entity.
+	TagID = "id"
+
+	// TagCreatedAt marks the time.Time field holding the creation date of an entity.
+	TagCreatedAt = "created_at"
+
+	// TagUpdatedAt marks the time.Time field holding the last modification date of an entity.
+	TagUpdatedAt = "updated_at"
+
+	// TagDeletedAt marks the field holding the deletion date of a soft deleted entity.
+	TagDeletedAt = "deleted_at"
+
+	// TagRef marks a field holding the id of an entity of another route, e.g.: `rip:"ref=albums"`.
+	TagRef = "ref"
+
+	// TagReadOnly marks a field that clients can not set, e.g. a computed field.
+	TagReadOnly = "readonly"
+
+	// TagWriteOnly marks a field that is never sent to clients, e.g. a password hash.
+	TagWriteOnly = "writeonly"
+)
+
+// HasRIPTag tells if the `rip` struct tag of f contains value.
+func HasRIPTag(f reflect.StructField, value string) bool {
 	ripTag, ok := f.Tag.Lookup("rip")
 	if !ok {
 		return false
 	}
 
 	tagValues := strings.Split(ripTag, ",")
-	if slices.Contains(tagValues, "id") {
+	if slices.Contains(tagValues, value) {
 		return true
 	}
 	return false
 }
+
+// IsReadOnly tells if clients can not set the field f: it has the `rip:"readonly"` struct tag,
+// or it is a timestamp managed by rip.
+func IsReadOnly(f reflect.StructField) bool {
+	return HasRIPTag(f, TagReadOnly) ||
+		HasRIPTag(f, TagCreatedAt) ||
+		HasRIPTag(f, TagUpdatedAt) ||
+		HasRIPTag(f, TagDeletedAt)
+}
+
+// RIPTagValue gets the value of key in the `rip` struct tag of f, e.g.: albums for `rip:"ref=albums"`.
+func RIPTagValue(f reflect.StructField, key string) (string, bool) {
+	ripTag, ok := f.Tag.Lookup("rip")
+	if !ok {
+		return "", false
+	}
+
+	for _, tagValue := range strings.Split(ripTag, ",") {
+		k, v, ok := strings.Cut(tagValue, "=")
+		if ok && k == key {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// FindTaggedField finds the first struct field of entity that has value in its `rip` struct tag.
+func FindTaggedField(entity any, value string) (reflect.Value, reflect.StructField, bool) {
+	v := reflect.ValueOf(entity)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, reflect.StructField{}, false
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, reflect.StructField{}, false
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() && HasRIPTag(f, value) {
+			return v.Field(i), f, true
+		}
+	}
+
+	return reflect.Value{}, reflect.StructField{}, false
+}
+
+// TaggedTime gets the value of the time.Time (or *time.Time) field of entity that has value
+// in its `rip` struct tag.
+func TaggedTime(entity any, value string) (time.Time, bool) {
+	v, _, ok := FindTaggedField(entity, value)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return time.Time{}, false
+		}
+		v = v.Elem()
+	}
+
+	t, ok := v.Interface().(time.Time)
+	return t, ok
+}
+
+// SetTaggedTime sets the time.Time (or *time.Time) field of entity that has value in its
+// `rip` struct tag. entity must be passed by reference.
+func SetTaggedTime(entity any, value string, t time.Time) bool {
+	v, _, ok := FindTaggedField(entity, value)
+	if !ok || !v.CanSet() {
+		return false
+	}
+
+	switch v.Type() {
+	case reflect.TypeFor[time.Time]():
+		v.Set(reflect.ValueOf(t))
+	case reflect.TypeFor[*time.Time]():
+		v.Set(reflect.ValueOf(&t))
+	default:
+		return false
+	}
+
+	return true
+}
+
+// IsDeleted tells if entity is soft deleted: its field with the `rip:"deleted_at"` struct tag
+// is set.
+func IsDeleted(entity any) bool {
+	v, _, ok := FindTaggedField(entity, TagDeletedAt)
+	return ok && !v.IsZero()
+}
+
+// ClearTaggedField resets the field of entity that has value in its `rip` struct tag.
+// entity must be passed by reference.
+func ClearTaggedField(entity any, value string) bool {
+	v, _, ok := FindTaggedField(entity, value)
+	if !ok || !v.CanSet() {
+		return false
+	}
+
+	v.SetZero()
+	return true
+}
@@ -0,0 +1,199 @@
+package ripreflect
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// StructType returns the struct type of t, dereferencing pointers.
+func StructType(t reflect.Type) (reflect.Type, bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, false
+	}
+
+	return t, true
+}
+
+// FieldByName finds the exported struct field of t called name, case-insensitively.
+// The name can also be the field name from its `json` struct tag.
+func FieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	t, ok := StructType(t)
+	if !ok {
+		return reflect.StructField{}, false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+
+		jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if jsonName != "" && jsonName != "-" && jsonName == name {
+			return f, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// JSONName returns the name of the struct field f in JSON: the name from its `json`
+// struct tag, or its Go name.
+func JSONName(f reflect.StructField) string {
+	jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if jsonName == "" || jsonName == "-" {
+		return f.Name
+	}
+
+	return jsonName
+}
+
+// ParseValue parses s as a value of type t.
+// Only basic kinds and time.Time (RFC 3339) are supported.
+func ParseValue(t reflect.Type, s string) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+
+	if t == timeType {
+		tm, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return v, fmt.Errorf("%q is not a RFC 3339 date", s)
+		}
+		v.Set(reflect.ValueOf(tm))
+		return v, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return v, fmt.Errorf("%q is not a boolean", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			return v, fmt.Errorf("%q is not an integer", s)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			return v, fmt.Errorf("%q is not a positive integer", s)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			return v, fmt.Errorf("%q is not a number", s)
+		}
+		v.SetFloat(f)
+	case reflect.Pointer:
+		elem, err := ParseValue(t.Elem(), s)
+		if err != nil {
+			return v, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(elem)
+		v.Set(p)
+	default:
+		return v, fmt.Errorf("type %s is not supported", t)
+	}
+
+	return v, nil
+}
+
+// IsComparable tells if values of type t can be ordered with [Compare].
+func IsComparable(t reflect.Type) bool {
+	if t == timeType {
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.String,
+		reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Pointer:
+		return IsComparable(t.Elem())
+	default:
+		return false
+	}
+}
+
+// Compare orders a and b that must be of the same type, see [IsComparable].
+// It returns -1 if a < b, 0 if a == b, and 1 if a > b. Nil pointers are before any value.
+func Compare(a, b reflect.Value) int {
+	if a.Kind() == reflect.Pointer {
+		switch {
+		case a.IsNil() && b.IsNil():
+			return 0
+		case a.IsNil():
+			return -1
+		case b.IsNil():
+			return 1
+		}
+		return Compare(a.Elem(), b.Elem())
+	}
+
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Compare(b.Interface().(time.Time))
+	}
+
+	switch a.Kind() {
+	case reflect.String:
+		return cmp.Compare(a.String(), b.String())
+	case reflect.Bool:
+		switch {
+		case a.Bool() == b.Bool():
+			return 0
+		case b.Bool():
+			return -1
+		default:
+			return 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	default:
+		panic("ripreflect: can not compare values of type " + a.Type().String())
+	}
+}
+
+// StructField gets the field called name of the struct ent, dereferencing pointers.
+func StructField(ent any, name string) (reflect.Value, bool) {
+	v := reflect.ValueOf(ent)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return reflect.Value{}, false
+	}
+
+	f := v.FieldByName(name)
+	return f, f.IsValid()
+}
@@ -0,0 +1,391 @@
+package rip
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// JSONPatchMimeType is the media type of a JSON Patch document (RFC 6902).
+const JSONPatchMimeType = "application/json-patch+json"
+
+// jsonPatchOperation is one operation of a JSON Patch document.
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// applyJSONPatch applies a JSON Patch document on ent as described in RFC 6902.
+// The operations are applied in order on a copy of the entity: if any of them fails,
+// including a "test" operation, ent is returned untouched with the error.
+func applyJSONPatch[Ent any](ent Ent, patch []byte) (Ent, error) {
+	var ops []jsonPatchOperation
+	err := json.Unmarshal(patch, &ops)
+	if err != nil {
+		return ent, Error{
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("malformed JSON patch document: %v", err),
+		}
+	}
+
+	original, err := entityToJSONDocument(ent)
+	if err != nil {
+		return ent, err
+	}
+
+	doc, err := entityToJSONDocument(ent)
+	if err != nil {
+		return ent, err
+	}
+
+	for i, op := range ops {
+		doc, err = op.apply(doc)
+		if err != nil {
+			e, ok := err.(Error)
+			if !ok {
+				e = Error{Status: http.StatusUnprocessableEntity, Detail: err.Error()}
+			}
+			e.Detail = fmt.Sprintf("JSON patch operation %d (%s): %s", i, op.Op, e.Detail)
+			return ent, e
+		}
+	}
+
+	return entityFromJSONDocument(ent, original, doc)
+}
+
+func (op jsonPatchOperation) apply(doc any) (any, error) {
+	if op.Path == nil {
+		return nil, Error{Status: http.StatusBadRequest, Detail: `missing "path" member`}
+	}
+	path := *op.Path
+
+	value := func() (any, error) {
+		if op.Value == nil {
+			return nil, patchError(http.StatusBadRequest, path, `missing "value" member`)
+		}
+		v, err := unmarshalJSONDocument(op.Value)
+		if err != nil {
+			return nil, patchError(http.StatusBadRequest, path, fmt.Sprintf("malformed value: %v", err))
+		}
+		return v, nil
+	}
+
+	from := func() (string, error) {
+		if op.From == nil {
+			return "", patchError(http.StatusBadRequest, path, `missing "from" member`)
+		}
+		return *op.From, nil
+	}
+
+	switch op.Op {
+	case "add":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+
+	case "remove":
+		doc, _, err := jsonPointerRemove(doc, path)
+		return doc, err
+
+	case "replace":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		doc, _, err = jsonPointerRemove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+
+	case "move":
+		fromPath, err := from()
+		if err != nil {
+			return nil, err
+		}
+		if fromPath == path {
+			return doc, nil
+		}
+		if strings.HasPrefix(path, fromPath+"/") {
+			return nil, patchError(http.StatusUnprocessableEntity, fromPath, "can not move a value into one of its children")
+		}
+		doc, v, err := jsonPointerRemove(doc, fromPath)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+
+	case "copy":
+		fromPath, err := from()
+		if err != nil {
+			return nil, err
+		}
+		v, err := jsonPointerGet(doc, fromPath)
+		if err != nil {
+			return nil, err
+		}
+		// values are copied so later operations don't modify both locations
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		v, err = unmarshalJSONDocument(b)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, v)
+
+	case "test":
+		v, err := value()
+		if err != nil {
+			return nil, err
+		}
+		current, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(current, v) {
+			return nil, patchError(http.StatusConflict, path, "test failed: value is different")
+		}
+		return doc, nil
+
+	default:
+		return nil, Error{Status: http.StatusBadRequest, Detail: fmt.Sprintf("unknown operation %q", op.Op)}
+	}
+}
+
+func patchError(status int, pointer, detail string) Error {
+	return Error{
+		Status: status,
+		Detail: detail,
+		Source: ErrorSource{
+			Pointer: pointer,
+		},
+	}
+}
+
+// parseJSONPointer splits a JSON pointer (RFC 6901) into its unescaped reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, patchError(http.StatusBadRequest, pointer, "malformed JSON pointer: it should start with /")
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		// ~1 must be replaced before ~0, see RFC 6901 section 4
+		t = strings.ReplaceAll(t, "~1", "/")
+		tokens[i] = strings.ReplaceAll(t, "~0", "~")
+	}
+
+	return tokens, nil
+}
+
+// jsonPointerParent resolves the container holding the value pointed by pointer,
+// and returns it with the last reference token.
+func jsonPointerParent(doc any, pointer string) (parent any, parentPointer, key string, err error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	key = tokens[len(tokens)-1]
+	parentPointer = pointer[:strings.LastIndex(pointer, "/")]
+	parent, err = jsonPointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return parent, parentPointer, key, nil
+}
+
+func jsonPointerGet(doc any, pointer string) (any, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	current := doc
+	for _, t := range tokens {
+		switch c := current.(type) {
+		case map[string]any:
+			v, ok := c[t]
+			if !ok {
+				return nil, patchError(http.StatusUnprocessableEntity, pointer, "path does not exist")
+			}
+			current = v
+		case []any:
+			i, err := arrayIndex(t, len(c)-1)
+			if err != nil {
+				return nil, patchError(http.StatusUnprocessableEntity, pointer, err.Error())
+			}
+			current = c[i]
+		default:
+			return nil, patchError(http.StatusUnprocessableEntity, pointer, "path does not exist")
+		}
+	}
+
+	return current, nil
+}
+
+func jsonPointerAdd(doc any, pointer string, value any) (any, error) {
+	if pointer == "" {
+		// it replaces the whole document
+		return value, nil
+	}
+
+	parent, parentPointer, key, err := jsonPointerParent(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := parent.(type) {
+	case map[string]any:
+		p[key] = value
+		return doc, nil
+	case []any:
+		i := len(p)
+		if key != "-" {
+			i, err = arrayIndex(key, len(p))
+			if err != nil {
+				return nil, patchError(http.StatusUnprocessableEntity, pointer, err.Error())
+			}
+		}
+		p = append(p, nil)
+		copy(p[i+1:], p[i:])
+		p[i] = value
+		// the slice header changed, we need to save it in the parent container
+		return jsonPointerReplace(doc, parentPointer, p)
+	default:
+		return nil, patchError(http.StatusUnprocessableEntity, pointer, "parent path is not an object or an array")
+	}
+}
+
+func jsonPointerRemove(doc any, pointer string) (newDoc, removed any, err error) {
+	if pointer == "" {
+		return nil, doc, nil
+	}
+
+	parent, parentPointer, key, err := jsonPointerParent(doc, pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch p := parent.(type) {
+	case map[string]any:
+		v, ok := p[key]
+		if !ok {
+			return nil, nil, patchError(http.StatusUnprocessableEntity, pointer, "path does not exist")
+		}
+		delete(p, key)
+		return doc, v, nil
+	case []any:
+		i, err := arrayIndex(key, len(p)-1)
+		if err != nil {
+			return nil, nil, patchError(http.StatusUnprocessableEntity, pointer, err.Error())
+		}
+		v := p[i]
+		p = append(p[:i:i], p[i+1:]...)
+		doc, err = jsonPointerReplace(doc, parentPointer, p)
+		return doc, v, err
+	default:
+		return nil, nil, patchError(http.StatusUnprocessableEntity, pointer, "path does not exist")
+	}
+}
+
+// jsonPointerReplace sets the value at an existing pointer location.
+func jsonPointerReplace(doc any, pointer string, value any) (any, error) {
+	if pointer == "" {
+		return value, nil
+	}
+
+	parent, _, key, err := jsonPointerParent(doc, pointer)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := parent.(type) {
+	case map[string]any:
+		p[key] = value
+	case []any:
+		i, err := arrayIndex(key, len(p)-1)
+		if err != nil {
+			return nil, patchError(http.StatusUnprocessableEntity, pointer, err.Error())
+		}
+		p[i] = value
+	}
+
+	return doc, nil
+}
+
+// arrayIndex parses an array index reference token, that should be between 0 and max included.
+func arrayIndex(token string, max int) (int, error) {
+	// leading zeros are not allowed, see RFC 6901 section 4
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("malformed array index %q", token)
+	}
+
+	i, err := strconv.Atoi(token)
+	if err != nil {
+		return 0, fmt.Errorf("malformed array index %q", token)
+	}
+
+	if i < 0 || i > max {
+		return 0, fmt.Errorf("array index %d out of bounds", i)
+	}
+
+	return i, nil
+}
+
+// jsonEqual compares 2 JSON documents as described in RFC 6902 section 4.6.
+func jsonEqual(a, b any) bool {
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, va := range a {
+			vb, ok := b[k]
+			if !ok || !jsonEqual(va, vb) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !jsonEqual(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		// 1 and 1.0 are the same number
+		fa, _, errA := big.ParseFloat(a.String(), 10, 256, big.ToNearestEven)
+		fb, _, errB := big.ParseFloat(b.String(), 10, 256, big.ToNearestEven)
+		if errA != nil || errB != nil {
+			return a == b
+		}
+		return fa.Cmp(fb) == 0
+	default:
+		return a == b
+	}
+}
@@ -0,0 +1,90 @@
+package rip
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// link is a RFC 8288 web link of a response.
+type link struct {
+	// Rel is the relation type of the link, e.g. "next".
+	Rel string
+	// HRef is the URI reference of the link target.
+	HRef string
+}
+
+// setLinkHeader sends the links in a Link response header.
+func setLinkHeader(w http.ResponseWriter, links []link) {
+	if len(links) == 0 {
+		return
+	}
+
+	var values []string
+	for _, l := range links {
+		values = append(values, fmt.Sprintf(`<%s>; rel=%q`, l.HRef, l.Rel))
+	}
+
+	w.Header().Set("Link", strings.Join(values, ", "))
+}
+
+// queryLink returns a link to the request path with the query parameters of r,
+// modified with params. An empty param value removes the parameter.
+func queryLink(r *http.Request, rel string, params url.Values) link {
+	query := r.URL.Query()
+	for k, v := range params {
+		if len(v) == 0 || v[0] == "" {
+			query.Del(k)
+			continue
+		}
+		query[k] = v
+	}
+
+	href := r.URL.Path
+	if len(query) > 0 {
+		href += "?" + query.Encode()
+	}
+
+	return link{
+		Rel:  rel,
+		HRef: href,
+	}
+}
+
+// pageLinks returns the first, prev, next and last links of a page of listed entities.
+// Without the total count of entities, there is no last link, and there is a next link
+// if the page is full.
+func pageLinks(r *http.Request, page, pageSize, listed, total int, hasTotal bool) []link {
+	pageLink := func(rel string, page int) link {
+		return queryLink(r, rel, url.Values{"page": {strconv.Itoa(page)}})
+	}
+
+	links := []link{pageLink("first", 1)}
+
+	lastPage := max((total+pageSize-1)/pageSize, 1)
+
+	if page > 1 {
+		prevPage := page - 1
+		if hasTotal {
+			// from a page after the end of the list, go back to the last one
+			prevPage = min(prevPage, lastPage)
+		}
+		links = append(links, pageLink("prev", prevPage))
+	}
+
+	hasNext := listed == pageSize
+	if hasTotal {
+		hasNext = page < lastPage
+	}
+	if hasNext {
+		links = append(links, pageLink("next", page+1))
+	}
+
+	if hasTotal {
+		links = append(links, pageLink("last", lastPage))
+	}
+
+	return links
+}
@@ -0,0 +1,100 @@
+package rip
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dolanor/rip/encoding/html"
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+// countMemoryProvider counts its entities.
+type countMemoryProvider[Ent any] struct {
+	*memoryProvider[Ent]
+}
+
+func (cp countMemoryProvider[Ent]) Count(ctx context.Context, filter Filter) (int, error) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+
+	return len(cp.mem), nil
+}
+
+func TestListPagination(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/products/", countMemoryProvider[product]{newProductProvider()}, WithCodecs(jsoncodec.Codec, html.NewEntityCodec("/products/"))))
+	mux.HandleFunc(HandleEntities("/uncounted/", newProductProvider(), WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	cases := map[string]struct {
+		path  string
+		link  string
+		total string
+	}{
+		"first page": {
+			path:  "/products/?page_size=3",
+			link:  `</products/?page=1&page_size=3>; rel="first", </products/?page=2&page_size=3>; rel="next", </products/?page=2&page_size=3>; rel="last"`,
+			total: "4",
+		},
+		"last page": {
+			path:  "/products/?page=2&page_size=3",
+			link:  `</products/?page=1&page_size=3>; rel="first", </products/?page=1&page_size=3>; rel="prev", </products/?page=2&page_size=3>; rel="last"`,
+			total: "4",
+		},
+		"after the last page": {
+			path:  "/products/?page=5&page_size=3",
+			link:  `</products/?page=1&page_size=3>; rel="first", </products/?page=2&page_size=3>; rel="prev", </products/?page=2&page_size=3>; rel="last"`,
+			total: "4",
+		},
+		"full page without count": {
+			path: "/uncounted/?page_size=2&page=2",
+			link: `</uncounted/?page=1&page_size=2>; rel="first", </uncounted/?page=1&page_size=2>; rel="prev", </uncounted/?page=3&page_size=2>; rel="next"`,
+		},
+		"partial page without count": {
+			path: "/uncounted/?page_size=3&page=2",
+			link: `</uncounted/?page=1&page_size=3>; rel="first", </uncounted/?page=1&page_size=3>; rel="prev"`,
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, err := s.Client().Get(s.URL + c.path)
+			panicErr(t, err)
+			resp.Body.Close()
+
+			if resp.Header.Get("Link") != c.link {
+				t.Fatalf("Link header is not\n%q:\n%q", c.link, resp.Header.Get("Link"))
+			}
+
+			if resp.Header.Get("X-Total-Count") != c.total {
+				t.Fatalf("X-Total-Count is not %q: %q", c.total, resp.Header.Get("X-Total-Count"))
+			}
+		})
+	}
+
+	t.Run("html pager", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, s.URL+"/products/?page_size=3", nil)
+		panicErr(t, err)
+		req.Header.Set("Accept", "text/html")
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		panicErr(t, err)
+
+		body := string(b)
+		if !strings.Contains(body, `href="/products/?page=2&amp;page_size=3" rel="next"`) {
+			t.Fatal("no next page in the HTML pager:", body)
+		}
+		if strings.Contains(body, `rel="prev"`) {
+			t.Fatal("previous page in the HTML pager of the first page:", body)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package rip
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// memoryProvider is a minimal in memory EntityProvider for any entity type,
+// that lists its entities ordered by ID.
+type memoryProvider[Ent any] struct {
+	mu  sync.Mutex
+	mem map[string]Ent
+}
+
+func newMemoryProvider[Ent any](ents ...Ent) *memoryProvider[Ent] {
+	mp := &memoryProvider[Ent]{
+		mem: map[string]Ent{},
+	}
+	for _, e := range ents {
+		mp.Create(context.Background(), e)
+	}
+	return mp
+}
+
+func (mp *memoryProvider[Ent]) Create(ctx context.Context, e Ent) (Ent, error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	id, err := ripreflect.GetID(e)
+	if err != nil {
+		return e, err
+	}
+	mp.mem[id] = e
+	return e, nil
+}
+
+func (mp *memoryProvider[Ent]) Get(ctx context.Context, id string) (Ent, error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	e, ok := mp.mem[id]
+	if !ok {
+		return e, ErrNotFound
+	}
+	return e, nil
+}
+
+func (mp *memoryProvider[Ent]) Update(ctx context.Context, e Ent) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	id, err := ripreflect.GetID(e)
+	if err != nil {
+		return err
+	}
+	_, ok := mp.mem[id]
+	if !ok {
+		return ErrNotFound
+	}
+	mp.mem[id] = e
+	return nil
+}
+
+func (mp *memoryProvider[Ent]) Delete(ctx context.Context, id string) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	_, ok := mp.mem[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(mp.mem, id)
+	return nil
+}
+
+func (mp *memoryProvider[Ent]) List(ctx context.Context, offset, limit int) ([]Ent, error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	ids := make([]string, 0, len(mp.mem))
+	for id := range mp.mem {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	ents := []Ent{}
+	for i := offset; i < len(ids) && i < offset+limit; i++ {
+		ents = append(ents, mp.mem[ids[i]])
+	}
+	return ents, nil
+}
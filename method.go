@@ -0,0 +1,94 @@
+package rip
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var (
+	collectionMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodOptions}
+	entityMethods     = []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	fieldMethods      = []string{http.MethodGet, http.MethodHead, http.MethodPut, http.MethodOptions}
+	batchMethods      = []string{http.MethodPost, http.MethodOptions}
+	restoreMethods    = []string{http.MethodPost, http.MethodOptions}
+)
+
+// allowedMethods returns the methods handled on requestPath, whether it is the
+// entity collection, the batch endpoint, an entity, the restore endpoint of an entity if
+// restorable, or an entity field.
+func allowedMethods(urlPath, requestPath string, restorable bool) []string {
+	id, field := getEntityField(urlPath, requestPath)
+	switch {
+	case id == "":
+		return collectionMethods
+	case isBatchPath(urlPath, requestPath):
+		return batchMethods
+	case restorable && isRestorePath(urlPath, requestPath):
+		return restoreMethods
+	case field == "":
+		return entityMethods
+	default:
+		return fieldMethods
+	}
+}
+
+// handleOptions describes the methods and the content types the route accepts on r.URL.Path.
+func handleOptions(w http.ResponseWriter, allowed []string, cfg entityRouteConfig) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+
+	if slices.Contains(allowed, http.MethodPost) {
+		// Accept-Post is defined in the Linked Data Platform specification:
+		// https://www.w3.org/TR/ldp/#header-accept-post
+		w.Header().Set("Accept-Post", strings.Join(acceptedMimeTypes(cfg), ", "))
+	}
+
+	if slices.Contains(allowed, http.MethodPut) {
+		// Accept-Put is defined in the Solid protocol, as Accept-Post for PUT:
+		// https://solidproject.org/TR/protocol#accept-put
+		w.Header().Set("Accept-Put", strings.Join(acceptedMimeTypes(cfg), ", "))
+	}
+
+	if slices.Contains(allowed, http.MethodPatch) {
+		// Accept-Patch is defined in RFC 5789 section 3.1
+		w.Header().Set("Accept-Patch", strings.Join(patchMimeTypes, ", "))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// acceptedMimeTypes lists the content types that can be decoded on this route, without duplicates.
+func acceptedMimeTypes(cfg entityRouteConfig) []string {
+	var mimeTypes []string
+	for _, m := range cfg.codecs.OrderedMimeTypes {
+		if !slices.Contains(mimeTypes, m) {
+			mimeTypes = append(mimeTypes, m)
+		}
+	}
+
+	return mimeTypes
+}
+
+// badMethodHandler answers 405 Method Not Allowed with the list of allowed methods.
+func badMethodHandler(w http.ResponseWriter, r *http.Request, allowed []string, cfg entityRouteConfig) {
+	accept, err := contentNegociateBestHeaderValue(r.Header, "Accept", cfg.codecs.OrderedMimeTypes)
+	if err != nil {
+		accept = ""
+	}
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, accept, Error{
+		Status: http.StatusMethodNotAllowed,
+		Detail: fmt.Sprintf("method %s not allowed, allowed methods: %s", r.Method, strings.Join(allowed, ", ")),
+	}, cfg)
+}
+
+// headResponseWriter discards the response body, so a GET handler can answer a HEAD request.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -0,0 +1,136 @@
+package rip
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dolanor/rip/encoding/json"
+	"github.com/dolanor/rip/encoding/xml"
+)
+
+func TestMethods(t *testing.T) {
+	up := newUserProvider()
+	up.Create(context.Background(), &user{Name: "Jane"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/users/", up, WithCodecs(json.Codec, xml.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	do := func(t *testing.T, method, path string) (*http.Response, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, s.URL+path, nil)
+		panicErr(t, err)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		panicErr(t, err)
+		return resp, string(b)
+	}
+
+	t.Run("head", func(t *testing.T) {
+		resp, body := do(t, http.MethodHead, "/users/Jane")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("head status code is not 200:", resp.StatusCode)
+		}
+		if body != "" {
+			t.Fatal("head response has a body:", body)
+		}
+		if resp.Header.Get("ETag") == "" {
+			t.Fatal("head response has no ETag")
+		}
+	})
+
+	t.Run("head not found", func(t *testing.T) {
+		resp, _ := do(t, http.MethodHead, "/users/Joe")
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatal("head status code is not 404:", resp.StatusCode)
+		}
+	})
+
+	t.Run("options collection", func(t *testing.T) {
+		resp, _ := do(t, http.MethodOptions, "/users/")
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatal("options status code is not 204:", resp.StatusCode)
+		}
+		if got := resp.Header.Get("Allow"); got != "GET, HEAD, POST, OPTIONS" {
+			t.Fatal("bad Allow header:", got)
+		}
+		if got := resp.Header.Get("Accept-Post"); got != "application/json, application/xml, text/xml" {
+			t.Fatal("bad Accept-Post header:", got)
+		}
+	})
+
+	t.Run("options entity", func(t *testing.T) {
+		resp, _ := do(t, http.MethodOptions, "/users/Jane")
+		if got := resp.Header.Get("Allow"); got != "GET, HEAD, PUT, PATCH, DELETE, OPTIONS" {
+			t.Fatal("bad Allow header:", got)
+		}
+		if got := resp.Header.Get("Accept-Put"); got != "application/json, application/xml, text/xml" {
+			t.Fatal("bad Accept-Put header:", got)
+		}
+		if got := resp.Header.Get("Accept-Patch"); got != "application/merge-patch+json, application/json-patch+json" {
+			t.Fatal("bad Accept-Patch header:", got)
+		}
+	})
+
+	t.Run("options field", func(t *testing.T) {
+		resp, _ := do(t, http.MethodOptions, "/users/Jane/name")
+		if got := resp.Header.Get("Allow"); got != "GET, HEAD, PUT, OPTIONS" {
+			t.Fatal("bad Allow header:", got)
+		}
+		if got := resp.Header.Get("Accept-Put"); got != "application/json, application/xml, text/xml" {
+			t.Fatal("bad Accept-Put header:", got)
+		}
+		if got := resp.Header.Get("Accept-Patch"); got != "" {
+			t.Fatal("Accept-Patch header on a field:", got)
+		}
+	})
+
+	cases := map[string]struct {
+		method string
+		path   string
+		allow  string
+	}{
+		"unknown method":    {"TRACE", "/users/Jane", "GET, HEAD, PUT, PATCH, DELETE, OPTIONS"},
+		"delete collection": {http.MethodDelete, "/users/", "GET, HEAD, POST, OPTIONS"},
+		"post on entity":    {http.MethodPost, "/users/Jane", "GET, HEAD, PUT, PATCH, DELETE, OPTIONS"},
+		"delete on field":   {http.MethodDelete, "/users/Jane/name", "GET, HEAD, PUT, OPTIONS"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			resp, _ := do(t, c.method, c.path)
+			if resp.StatusCode != http.StatusMethodNotAllowed {
+				t.Fatal("status code is not 405:", resp.StatusCode)
+			}
+			if got := resp.Header.Get("Allow"); got != c.allow {
+				t.Fatal("bad Allow header:", got)
+			}
+		})
+	}
+}
+
+func TestHandleMethodNotAllowed(t *testing.T) {
+	s := httptest.NewServer(Handle(http.MethodPost, func(ctx context.Context, input string) (string, error) {
+		return input, nil
+	}))
+	defer s.Close()
+
+	resp, err := s.Client().Get(s.URL)
+	panicErr(t, err)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatal("status code is not 405:", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Allow"); got != http.MethodPost {
+		t.Fatal("bad Allow header:", got)
+	}
+}
@@ -0,0 +1,121 @@
+package rip
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// NestedRoute is a route that can be mounted under the entities of another route with
+// [WithNestedRoute].
+type NestedRoute interface {
+	Route
+
+	// nestedHandler returns the handler of the route, created once: it handles the requests
+	// at the path where the route is mounted, see [withMountedPath].
+	nestedHandler() http.HandlerFunc
+}
+
+type parentIDsKey struct{}
+
+type mountedPathKey struct{}
+
+// withMountedPath tells the handler of a nested route that it is mounted at urlPath,
+// e.g. /users/42/albums/
+func withMountedPath(ctx context.Context, urlPath string) context.Context {
+	return context.WithValue(ctx, mountedPathKey{}, urlPath)
+}
+
+// mountedPath returns the path where the route of urlPath handles the request:
+// the path where it is mounted if it is a nested route, otherwise urlPath.
+func mountedPath(ctx context.Context, urlPath string) string {
+	mounted, ok := ctx.Value(mountedPathKey{}).(string)
+	if !ok {
+		return urlPath
+	}
+
+	return mounted
+}
+
+// ParentIDs returns the ids of the parent entities of a request on a nested route,
+// from the outermost to the innermost one.
+// e.g.: ["42", "7"] for /users/42/albums/7/tracks/
+func ParentIDs(ctx context.Context) []string {
+	ids, _ := ctx.Value(parentIDsKey{}).([]string)
+	return ids
+}
+
+// ParentID returns the id of the direct parent entity of a request on a nested route.
+// e.g.: "42" for /users/42/albums/
+func ParentID(ctx context.Context) (string, bool) {
+	ids := ParentIDs(ctx)
+	if len(ids) == 0 {
+		return "", false
+	}
+
+	return ids[len(ids)-1], true
+}
+
+func withParentID(ctx context.Context, id string) context.Context {
+	ids := append(slices.Clone(ParentIDs(ctx)), id)
+	return context.WithValue(ctx, parentIDsKey{}, ids)
+}
+
+// nestedRouteName returns the name of a nested route, the path segment after the parent id.
+func nestedRouteName(route NestedRoute) string {
+	return strings.Trim(route.Path(), "/")
+}
+
+// findNestedRoute finds the nested route of requestPath, e.g.: for /users/42/albums/7
+// it returns the albums route mounted on the /users/ route, with the parent id 42.
+// The nested route is matched with its whole name, e.g. /users/42/api/albums/7 for api/albums.
+func findNestedRoute(urlPath, requestPath string, routes map[string]NestedRoute) (parentID string, route NestedRoute, ok bool) {
+	if len(routes) == 0 {
+		return "", nil, false
+	}
+
+	rest := strings.TrimPrefix(requestPath, urlPath)
+	rest = strings.TrimLeft(rest, "/")
+
+	parentID, rest, ok = strings.Cut(rest, "/")
+	if !ok || parentID == "" {
+		return "", nil, false
+	}
+
+	// the name of a nested route can have several path segments, e.g. api/albums:
+	// the longest one matching the path after the parent id is chosen
+	var name string
+	for n, r := range routes {
+		matches := rest == n || strings.HasPrefix(rest, n+"/")
+		if matches && len(n) > len(name) {
+			name, route = n, r
+		}
+	}
+	if route == nil {
+		return "", nil, false
+	}
+
+	return parentID, route, true
+}
+
+// handleNested checks that the parent entity exists, and passes the request to the nested route.
+func handleNested[Ent any](w http.ResponseWriter, r *http.Request, urlPath, parentID string, route NestedRoute, get getFunc[Ent], cfg entityRouteConfig) {
+	accept, err := contentNegociateBestHeaderValue(r.Header, "Accept", cfg.codecs.OrderedMimeTypes)
+	if err != nil {
+		accept = ""
+	}
+
+	_, err = get(r.Context(), parentID)
+	if err != nil {
+		writeError(w, accept, err, cfg)
+		return
+	}
+
+	nestedPath := path.Join(urlPath, parentID, nestedRouteName(route)) + "/"
+	ctx := withParentID(r.Context(), parentID)
+	ctx = withMountedPath(ctx, nestedPath)
+
+	route.nestedHandler()(w, r.WithContext(ctx))
+}
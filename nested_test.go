@@ -0,0 +1,141 @@
+package rip
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+type album struct {
+	ID     string `json:"id" xml:"id"`
+	UserID string `json:"user_id" xml:"user_id"`
+	Title  string `json:"title" xml:"title"`
+}
+
+// albumMemoryProvider stores the albums of the parent entity of the request.
+type albumMemoryProvider struct {
+	*memoryProvider[album]
+}
+
+func (ap albumMemoryProvider) Create(ctx context.Context, a album) (album, error) {
+	a.UserID, _ = ParentID(ctx)
+	return ap.memoryProvider.Create(ctx, a)
+}
+
+func (ap albumMemoryProvider) List(ctx context.Context, offset, limit int) ([]album, error) {
+	all, err := ap.memoryProvider.List(ctx, 0, len(ap.mem))
+	if err != nil {
+		return nil, err
+	}
+
+	userID, _ := ParentID(ctx)
+	albums := []album{}
+	for _, a := range all {
+		if a.UserID == userID {
+			albums = append(albums, a)
+		}
+	}
+
+	return albums[min(offset, len(albums)):min(offset+limit, len(albums))], nil
+}
+
+func TestNestedRoute(t *testing.T) {
+	ap := albumMemoryProvider{newMemoryProvider(
+		album{ID: "1", UserID: "1", Title: "debut"},
+		album{ID: "2", UserID: "2", Title: "live"},
+	)}
+
+	albums := NewEntityRoute("albums", ap, WithCodecs(jsoncodec.Codec))
+	products := NewEntityRoute("/products/", newProductProvider(), WithCodecs(jsoncodec.Codec), WithNestedRoute(albums))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(products.Path(), products.Handler())
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	t.Run("list", func(t *testing.T) {
+		resp, err := s.Client().Get(s.URL + "/products/1/albums/")
+		panicErr(t, err)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+
+		var list []album
+		err = json.NewDecoder(resp.Body).Decode(&list)
+		panicErr(t, err)
+		if len(list) != 1 || list[0].ID != "1" {
+			t.Fatal("bad albums of the parent entity:", list)
+		}
+	})
+
+	t.Run("create", func(t *testing.T) {
+		resp, err := s.Client().Post(s.URL+"/products/2/albums/", "application/json", strings.NewReader(`{"id": "3", "title": "remixes"}`))
+		panicErr(t, err)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatal("status code is not 201:", resp.StatusCode)
+		}
+		if resp.Header.Get("Location") != "/products/2/albums/3" {
+			t.Fatal("bad Location header:", resp.Header.Get("Location"))
+		}
+
+		a, err := ap.Get(context.Background(), "3")
+		panicErr(t, err)
+		if a.UserID != "2" {
+			t.Fatal("the provider did not get the parent id:", a.UserID)
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		resp, err := s.Client().Get(s.URL + "/products/1/albums/1")
+		panicErr(t, err)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+	})
+
+	t.Run("missing parent", func(t *testing.T) {
+		resp, err := s.Client().Get(s.URL + "/products/42/albums/")
+		panicErr(t, err)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatal("status code is not 404:", resp.StatusCode)
+		}
+	})
+
+	t.Run("multi-segment path", func(t *testing.T) {
+		albums := NewEntityRoute("/api/albums/", ap, WithCodecs(jsoncodec.Codec))
+		users := NewEntityRoute("/users/", newProductProvider(), WithCodecs(jsoncodec.Codec), WithNestedRoute(albums))
+
+		req := httptest.NewRequest(http.MethodGet, "/users/1/api/albums/1", nil)
+		rec := httptest.NewRecorder()
+		users.Handler()(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatal("status code is not 200:", rec.Code, rec.Body.String())
+		}
+
+		if users.OpenAPISchema().Paths.Value("/users/{product_id}/api/albums/{id}") == nil {
+			t.Fatal("no OpenAPI path:", users.OpenAPISchema().Paths.InMatchingOrder())
+		}
+	})
+
+	t.Run("openapi", func(t *testing.T) {
+		paths := products.OpenAPISchema().Paths
+		for _, p := range []string{"/products/{product_id}/albums/", "/products/{product_id}/albums/{id}"} {
+			item := paths.Value(p)
+			if item == nil {
+				t.Fatalf("no OpenAPI path %s: %v", p, paths.InMatchingOrder())
+			}
+			if item.Parameters.GetByInAndName("path", "product_id") == nil {
+				t.Fatalf("no parent id parameter on %s", p)
+			}
+		}
+	})
+}
@@ -4,7 +4,10 @@ import (
 	_ "embed"
 	"html/template"
 	"net/http"
+	"reflect"
+	"slices"
 
+	"github.com/dolanor/rip/internal/ripreflect"
 	"github.com/getkin/kin-openapi/openapi3"
 )
 
@@ -53,3 +56,100 @@ func handleSwaggerUI(title string) http.HandlerFunc {
 		}
 	}
 }
+
+// fieldSchemaCustomizer writes the constraints of the `validate` struct tags of the fields
+// of a struct into its OpenAPI schema, and marks its read-only and write-only fields.
+func fieldSchemaCustomizer(name string, t reflect.Type, tag reflect.StructTag, schema *openapi3.Schema) error {
+	t, ok := ripreflect.StructType(t)
+	if !ok || t == timeType {
+		return nil
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fr, err := parseFieldRules(f)
+		if err != nil {
+			return err
+		}
+
+		propName := ripreflect.JSONName(f)
+		prop, ok := schema.Properties[propName]
+		if !ok || prop.Value == nil {
+			continue
+		}
+
+		if fr.required && !slices.Contains(schema.Required, propName) {
+			schema.Required = append(schema.Required, propName)
+		}
+
+		fr.applySchema(f.Type, prop.Value)
+		prop.Value.ReadOnly = ripreflect.IsReadOnly(f)
+		prop.Value.WriteOnly = ripreflect.HasRIPTag(f, ripreflect.TagWriteOnly)
+	}
+
+	return nil
+}
+
+// applySchema writes the constraints of the field of type t into its schema.
+func (fr fieldRules) applySchema(t reflect.Type, schema *openapi3.Schema) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	minLength, maxLength := fr.min, fr.max
+	if fr.length != nil {
+		length := float64(*fr.length)
+		minLength, maxLength = &length, &length
+	}
+
+	switch {
+	case t.Kind() == reflect.String:
+		if minLength != nil {
+			schema.MinLength = uint64(*minLength)
+		}
+		if maxLength != nil {
+			schema.MaxLength = openapi3.Uint64Ptr(uint64(*maxLength))
+		}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if minLength != nil {
+			schema.MinItems = uint64(*minLength)
+		}
+		if maxLength != nil {
+			schema.MaxItems = openapi3.Uint64Ptr(uint64(*maxLength))
+		}
+	case t.Kind() == reflect.Map:
+		if minLength != nil {
+			schema.MinProps = uint64(*minLength)
+		}
+		if maxLength != nil {
+			schema.MaxProps = openapi3.Uint64Ptr(uint64(*maxLength))
+		}
+	case isNumber(t):
+		schema.Min = fr.min
+		schema.Max = fr.max
+	}
+
+	for _, v := range fr.enum {
+		value, err := ripreflect.ParseValue(t, v)
+		if err != nil {
+			schema.Enum = append(schema.Enum, v)
+			continue
+		}
+		schema.Enum = append(schema.Enum, value.Interface())
+	}
+
+	switch {
+	case fr.email:
+		schema.Format = "email"
+	case fr.url:
+		schema.Format = "uri"
+	}
+
+	if fr.pattern != nil {
+		schema.Pattern = fr.pattern.String()
+	}
+}
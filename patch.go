@@ -0,0 +1,322 @@
+package rip
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/dolanor/rip/encoding"
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// MergePatchMimeType is the media type of a JSON Merge Patch document (RFC 7396).
+const MergePatchMimeType = "application/merge-patch+json"
+
+// patchMimeTypes lists the patch document formats accepted on PATCH requests.
+var patchMimeTypes = []string{
+	MergePatchMimeType,
+	JSONPatchMimeType,
+}
+
+func patchPathID[Ent any](urlPath, method string, update updateFunc[Ent], get getFunc[Ent], etag etagFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, _, _, _, accept, _, err := getIDAndEditMode(w, r, method, urlPath, cfg)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		// the patch document is not a registered codec, so if the client didn't ask for a specific
+		// representation, we answer with the default codec.
+		if accept == "" {
+			accept = encoding.DefaultCodecKey
+		}
+
+		patchType, err := patchContentType(r.Header)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		patch, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, accept, fmt.Errorf("read patch document: %w", err), cfg)
+			return
+		}
+
+		ent, err := get(r.Context(), id)
+		if err != nil {
+			writeError(w, accept, fmt.Errorf("can not get original entity: %w", err), cfg)
+			return
+		}
+
+		// the patch is applied on the entity we just got, so we can check its version directly
+		err = checkIfMatchEntity(r, ent, etag, cfg)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		switch patchType {
+		case MergePatchMimeType:
+			ent, err = applyMergePatch(ent, patch)
+		case JSONPatchMimeType:
+			ent, err = applyJSONPatch(ent, patch)
+		}
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		// the entity ID in the path is authoritative, a patch can not move an entity.
+		err = ripreflect.SetID(&ent, id)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		ent, err = update(r.Context(), ent)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		err = setETag(w, r, accept, ent, etag)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+		setLastModified(w, ent)
+
+		rrw := encoding.RequestResponseWriter{
+			ResponseWriter: w,
+			Request:        r,
+		}
+
+		err = acceptEncoder(rrw, accept, encoding.EditOff, cfg).Encode(ent)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+	}
+}
+
+// patchContentType returns the patch document format of the request,
+// or an error if it is not supported.
+func patchContentType(header http.Header) (string, error) {
+	contentType := header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil {
+		for _, m := range patchMimeTypes {
+			if mediaType == m {
+				return m, nil
+			}
+		}
+	}
+
+	return "", Error{
+		Status: http.StatusUnsupportedMediaType,
+		Detail: fmt.Sprintf("unsupported patch document type %q, supported types: %s", contentType, strings.Join(patchMimeTypes, ", ")),
+		Source: ErrorSource{
+			Header: "Content-Type",
+		},
+	}
+}
+
+// applyMergePatch applies a JSON Merge Patch document on ent as described in RFC 7396.
+// A null value in the patch resets the entity field to its zero value.
+func applyMergePatch[Ent any](ent Ent, patch []byte) (Ent, error) {
+	patchDoc, err := unmarshalJSONDocument(patch)
+	if err != nil {
+		return ent, Error{
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("malformed merge patch document: %v", err),
+		}
+	}
+
+	doc, err := entityToJSONDocument(ent)
+	if err != nil {
+		return ent, err
+	}
+
+	original, err := entityToJSONDocument(ent)
+	if err != nil {
+		return ent, err
+	}
+
+	return entityFromJSONDocument(ent, original, mergePatch(doc, patchDoc))
+}
+
+// mergePatch is the MergePatch(Target, Patch) function from RFC 7396.
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergePatch(targetObj[k], v)
+	}
+
+	return targetObj
+}
+
+// entityToJSONDocument converts ent to its generic JSON representation
+// (maps, slices, and scalar values) so it can be patched.
+func entityToJSONDocument(ent any) (any, error) {
+	b, err := json.Marshal(ent)
+	if err != nil {
+		return nil, fmt.Errorf("encode entity to JSON document: %w", err)
+	}
+
+	return unmarshalJSONDocument(b)
+}
+
+// entityFromJSONDocument returns a copy of ent with the members of the patched JSON document
+// doc which differ from original, the JSON document of ent.
+// The fields of the members removed from the document are reset to their zero value, and the
+// fields which are not in JSON (e.g. with the `json:"-"` struct tag) keep their value.
+func entityFromJSONDocument[Ent any](ent Ent, original, doc any) (Ent, error) {
+	patched := copyEntity(ent)
+	v, isStruct := entityStruct(&patched)
+	originalObj, originalIsObj := original.(map[string]any)
+	docObj, docIsObj := doc.(map[string]any)
+	if !isStruct || !originalIsObj || !docIsObj {
+		// the fields of the entity can not be patched one by one
+		var zero Ent
+		return decodeJSONDocument(zero, doc)
+	}
+
+	changed := map[string]any{}
+	for k, dv := range docObj {
+		ov, ok := originalObj[k]
+		if !ok || !jsonEqual(ov, dv) {
+			changed[k] = dv
+		}
+	}
+
+	clearJSONFields(v, func(name string) bool {
+		for k := range changed {
+			if strings.EqualFold(k, name) {
+				return true
+			}
+		}
+
+		_, inOriginal := originalObj[name]
+		_, inDoc := docObj[name]
+		return inOriginal && !inDoc
+	})
+
+	return decodeJSONDocument(patched, changed)
+}
+
+// copyEntity returns a copy of ent, which doesn't share the entity pointed by ent.
+func copyEntity[Ent any](ent Ent) Ent {
+	v := reflect.ValueOf(&ent).Elem()
+	if v.Kind() == reflect.Pointer && !v.IsNil() {
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(v.Elem())
+		v.Set(c)
+	}
+
+	return ent
+}
+
+// clearJSONFields zeroes the fields of the struct v encoded in JSON as a cleared member,
+// including the ones of its embedded structs.
+func clearJSONFields(v reflect.Value, cleared func(name string) bool) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+		if f.Anonymous && name == "" {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() || !fv.CanSet() {
+					continue
+				}
+
+				// the embedded struct is changed on a copy, like the entity
+				c := reflect.New(fv.Type().Elem())
+				c.Elem().Set(fv.Elem())
+				fv.Set(c)
+				fv = c.Elem()
+			}
+
+			if fv.Kind() == reflect.Struct {
+				clearJSONFields(fv, cleared)
+				continue
+			}
+		}
+
+		if !f.IsExported() || !fv.CanSet() {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		if cleared(name) {
+			fv.SetZero()
+		}
+	}
+}
+
+// decodeJSONDocument decodes the generic JSON document doc in ent.
+func decodeJSONDocument[Ent any](ent Ent, doc any) (Ent, error) {
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return ent, fmt.Errorf("encode patched JSON document: %w", err)
+	}
+
+	err = json.Unmarshal(b, &ent)
+	if err != nil {
+		e := Error{
+			Status: http.StatusUnprocessableEntity,
+			Detail: fmt.Sprintf("patched entity is not valid: %v", err),
+		}
+
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			e.Source.Pointer = "/" + strings.ReplaceAll(typeErr.Field, ".", "/")
+		}
+
+		return ent, e
+	}
+
+	return ent, nil
+}
+
+// unmarshalJSONDocument decodes b keeping numbers as [json.Number] so
+// integer values don't lose precision through float64.
+func unmarshalJSONDocument(b []byte) (any, error) {
+	var doc any
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	err := dec.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
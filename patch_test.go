@@ -0,0 +1,273 @@
+package rip
+
+import (
+	gjson "encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dolanor/rip/encoding/json"
+)
+
+func TestMergePatch(t *testing.T) {
+	up := newUserProvider()
+	up.mem["Jane"] = user{
+		Name:         "Jane",
+		EmailAddress: "jane@example.com",
+		BirthDate:    time.Date(2009, time.November, 1, 23, 0, 0, 0, time.UTC),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/users/", up, WithCodecs(json.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	c := s.Client()
+
+	patch := func(t *testing.T, contentType, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPatch, s.URL+"/users/Jane", strings.NewReader(body))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.Do(req)
+		panicErr(t, err)
+		return resp
+	}
+
+	t.Run("update field", func(t *testing.T) {
+		resp := patch(t, MergePatchMimeType, `{"email_address": "jane@example.org"}`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("patch status code is not 200:", resp.StatusCode)
+		}
+
+		var u user
+		err := gjson.NewDecoder(resp.Body).Decode(&u)
+		panicErr(t, err)
+		if u.EmailAddress != "jane@example.org" {
+			t.Fatal("email not patched:", u.EmailAddress)
+		}
+		if !u.BirthDate.Equal(up.mem["Jane"].BirthDate) || up.mem["Jane"].EmailAddress != "jane@example.org" {
+			t.Fatal("patched user not saved:", up.mem["Jane"])
+		}
+	})
+
+	t.Run("null deletes field", func(t *testing.T) {
+		resp := patch(t, MergePatchMimeType+"; charset=utf-8", `{"email_address": null, "name": "Joe"}`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("patch status code is not 200:", resp.StatusCode)
+		}
+
+		u := up.mem["Jane"]
+		if u.EmailAddress != "" {
+			t.Fatal("email not deleted:", u.EmailAddress)
+		}
+		if u.Name != "Jane" {
+			t.Fatal("patch should not change the entity ID:", u.Name)
+		}
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		resp := patch(t, MergePatchMimeType, `{"birth_date": 42}`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatal("patch status code is not 422:", resp.StatusCode)
+		}
+
+		var e Error
+		err := gjson.NewDecoder(resp.Body).Decode(&e)
+		panicErr(t, err)
+		if e.Source.Pointer != "/birth_date" {
+			t.Fatal("bad error source pointer:", e.Source.Pointer)
+		}
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		resp := patch(t, "application/json", `{"email_address": "jane@example.org"}`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Fatal("patch status code is not 415:", resp.StatusCode)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPatch, s.URL+"/users/Nobody", strings.NewReader(`{}`))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", MergePatchMimeType)
+
+		resp, err := c.Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatal("patch status code is not 404:", resp.StatusCode)
+		}
+	})
+}
+
+// vault has a field which is not in JSON.
+type vault struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Secret string `json:"-"`
+}
+
+func TestPatchFieldNotInJSON(t *testing.T) {
+	cases := map[string]struct {
+		contentType string
+		body        string
+	}{
+		"merge patch": {contentType: MergePatchMimeType, body: `{"name": "b"}`},
+		"json patch":  {contentType: JSONPatchMimeType, body: `[{"op": "replace", "path": "/name", "value": "b"}]`},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			vp := newMemoryProvider(vault{ID: "1", Name: "a", Secret: "s3cr3t"})
+
+			mux := http.NewServeMux()
+			mux.HandleFunc(HandleEntities("/vaults/", vp, WithCodecs(json.Codec)))
+			s := httptest.NewServer(mux)
+			defer s.Close()
+
+			req, err := http.NewRequest(http.MethodPatch, s.URL+"/vaults/1", strings.NewReader(c.body))
+			panicErr(t, err)
+			req.Header.Set("Content-Type", c.contentType)
+
+			resp, err := s.Client().Do(req)
+			panicErr(t, err)
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatal("patch status code is not 200:", resp.StatusCode)
+			}
+
+			v := vp.mem["1"]
+			if v.Name != "b" || v.Secret != "s3cr3t" {
+				t.Fatal("bad patched vault:", v)
+			}
+		})
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	up := newUserProvider()
+	up.mem["Jane"] = user{
+		Name:         "Jane",
+		EmailAddress: "jane@example.com",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/users/", up, WithCodecs(json.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	c := s.Client()
+
+	patch := func(t *testing.T, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPatch, s.URL+"/users/Jane", strings.NewReader(body))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", JSONPatchMimeType)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.Do(req)
+		panicErr(t, err)
+		return resp
+	}
+
+	t.Run("test and replace", func(t *testing.T) {
+		resp := patch(t, `[
+			{"op": "test", "path": "/email_address", "value": "jane@example.com"},
+			{"op": "replace", "path": "/email_address", "value": "jane@example.org"}
+		]`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("patch status code is not 200:", resp.StatusCode)
+		}
+
+		if up.mem["Jane"].EmailAddress != "jane@example.org" {
+			t.Fatal("email not patched:", up.mem["Jane"].EmailAddress)
+		}
+	})
+
+	t.Run("failed test is atomic", func(t *testing.T) {
+		resp := patch(t, `[
+			{"op": "replace", "path": "/email_address", "value": "jane@example.net"},
+			{"op": "test", "path": "/email_address", "value": "jane@example.com"}
+		]`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusConflict {
+			t.Fatal("patch status code is not 409:", resp.StatusCode)
+		}
+
+		var e Error
+		err := gjson.NewDecoder(resp.Body).Decode(&e)
+		panicErr(t, err)
+		if e.Source.Pointer != "/email_address" {
+			t.Fatal("bad error source pointer:", e.Source.Pointer)
+		}
+
+		if up.mem["Jane"].EmailAddress != "jane@example.org" {
+			t.Fatal("failed patch should not be saved:", up.mem["Jane"].EmailAddress)
+		}
+	})
+
+	t.Run("copy and remove", func(t *testing.T) {
+		resp := patch(t, `[
+			{"op": "copy", "from": "/email_address", "path": "/birth_date"},
+			{"op": "remove", "path": "/missing"}
+		]`)
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusUnprocessableEntity {
+			t.Fatal("patch status code is not 422:", resp.StatusCode)
+		}
+
+		var e Error
+		err := gjson.NewDecoder(resp.Body).Decode(&e)
+		panicErr(t, err)
+		if e.Source.Pointer != "/missing" {
+			t.Fatal("bad error source pointer:", e.Source.Pointer)
+		}
+	})
+}
+
+func TestJSONPatchOperations(t *testing.T) {
+	cases := map[string]struct {
+		doc   string
+		patch string
+		want  string
+	}{
+		"add to object":      {`{"a": 1}`, `[{"op": "add", "path": "/b", "value": [1]}]`, `{"a": 1, "b": [1]}`},
+		"add to array":       {`{"a": [1, 3]}`, `[{"op": "add", "path": "/a/1", "value": 2}]`, `{"a": [1, 2, 3]}`},
+		"append to array":    {`{"a": [1]}`, `[{"op": "add", "path": "/a/-", "value": 2}]`, `{"a": [1, 2]}`},
+		"remove from array":  {`{"a": [1, 2, 3]}`, `[{"op": "remove", "path": "/a/0"}]`, `{"a": [2, 3]}`},
+		"move":               {`{"a": {"b": 1}}`, `[{"op": "move", "from": "/a/b", "path": "/c"}]`, `{"a": {}, "c": 1}`},
+		"escaped pointer":    {`{"a/b": 1, "m~n": 2}`, `[{"op": "remove", "path": "/a~1b"}, {"op": "remove", "path": "/m~0n"}]`, `{}`},
+		"test number format": {`{"a": 1}`, `[{"op": "test", "path": "/a", "value": 1.0}]`, `{"a": 1}`},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			var doc map[string]any
+			err := gjson.Unmarshal([]byte(c.doc), &doc)
+			panicErr(t, err)
+
+			got, err := applyJSONPatch(doc, []byte(c.patch))
+			panicErr(t, err)
+
+			var want map[string]any
+			err = gjson.Unmarshal([]byte(c.want), &want)
+			panicErr(t, err)
+
+			gotJSON, _ := gjson.Marshal(got)
+			wantJSON, _ := gjson.Marshal(want)
+			if string(gotJSON) != string(wantJSON) {
+				t.Fatalf("patched document:\nwant=%s\ngot= %s", wantJSON, gotJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package rip
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ReturnPreference is the kind of response a client prefers for a request creating
+// a resource, as defined in RFC 7240 section 4.2.
+type ReturnPreference string
+
+const (
+	// ReturnRepresentation sends the created entity in the response body.
+	ReturnRepresentation ReturnPreference = "representation"
+
+	// ReturnMinimal sends an empty response body, the created entity
+	// can be found with the Location header.
+	ReturnMinimal ReturnPreference = "minimal"
+)
+
+// returnPreference selects the response preference for r.
+// The `Prefer: return=…` header of the client is only honoured if the route has been
+// configured with [WithPreferReturn], applied is then true.
+func returnPreference(r *http.Request, cfg entityRouteConfig) (preference ReturnPreference, applied bool) {
+	if cfg.preferReturn == "" {
+		return ReturnRepresentation, false
+	}
+
+	value, ok := preferValue(r.Header, "return")
+	if !ok {
+		return cfg.preferReturn, false
+	}
+
+	switch p := ReturnPreference(value); p {
+	case ReturnRepresentation, ReturnMinimal:
+		return p, true
+	default:
+		// unknown preferences must be ignored
+		return cfg.preferReturn, false
+	}
+}
+
+// preferValue gets the value of the preference in the Prefer request headers.
+//
+// e.g.: "minimal" for the "return" preference of
+//
+//	Prefer: respond-async, return=minimal; foo="bar"
+func preferValue(header http.Header, preference string) (string, bool) {
+	for _, h := range header.Values("Prefer") {
+		for _, pref := range strings.Split(h, ",") {
+			// we don't need the preference parameters
+			pref, _, _ = strings.Cut(pref, ";")
+
+			name, value, _ := strings.Cut(pref, "=")
+			if !strings.EqualFold(strings.TrimSpace(name), preference) {
+				continue
+			}
+
+			return strings.Trim(strings.TrimSpace(value), `"`), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,66 @@
+package rip
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dolanor/rip/encoding/json"
+)
+
+func TestCreateLocationAndPrefer(t *testing.T) {
+	cases := map[string]struct {
+		options        []EntityRouteOption
+		prefer         string
+		wantBody       bool
+		wantPreference string
+	}{
+		"no option":                  {nil, "return=minimal", true, ""},
+		"default representation":     {[]EntityRouteOption{WithPreferReturn(ReturnRepresentation)}, "", true, ""},
+		"default minimal":            {[]EntityRouteOption{WithPreferReturn(ReturnMinimal)}, "", false, ""},
+		"prefer minimal":             {[]EntityRouteOption{WithPreferReturn(ReturnRepresentation)}, "handling=lenient, return=minimal", false, "return=minimal"},
+		"prefer representation":      {[]EntityRouteOption{WithPreferReturn(ReturnMinimal)}, `return="representation"; foo=bar`, true, "return=representation"},
+		"unknown preference ignored": {[]EntityRouteOption{WithPreferReturn(ReturnMinimal)}, "return=everything", false, ""},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			up := newUserProvider()
+			mux := http.NewServeMux()
+			mux.HandleFunc(HandleEntities("/users/", up, append(c.options, WithCodecs(json.Codec))...))
+			s := httptest.NewServer(mux)
+			defer s.Close()
+
+			req, err := http.NewRequest(http.MethodPost, s.URL+"/users/", strings.NewReader(`{"name": "Jane Doe"}`))
+			panicErr(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if c.prefer != "" {
+				req.Header.Set("Prefer", c.prefer)
+			}
+
+			resp, err := s.Client().Do(req)
+			panicErr(t, err)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatal("post status code is not 201:", resp.StatusCode)
+			}
+
+			if got := resp.Header.Get("Location"); got != "/users/Jane%20Doe" {
+				t.Fatal("bad Location header:", got)
+			}
+
+			if got := resp.Header.Get("Preference-Applied"); got != c.wantPreference {
+				t.Fatal("bad Preference-Applied header:", got)
+			}
+
+			b, err := io.ReadAll(resp.Body)
+			panicErr(t, err)
+			if (len(b) > 0) != c.wantBody {
+				t.Fatalf("unexpected body: %q", b)
+			}
+		})
+	}
+}
@@ -5,10 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"reflect"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/samonzeweb/godb"
 
+	"github.com/dolanor/rip"
 	"github.com/dolanor/rip/internal/ripreflect"
 )
 
@@ -129,3 +132,75 @@ func (ep *godbEntityProvider[Ent]) List(ctx context.Context, offset, limit int)
 
 	return ee, nil
 }
+
+// ListSorted lists the entities ordered by sort.
+// The provider doesn't implement [rip.FilterLister], so filter is always empty.
+func (ep *godbEntityProvider[Ent]) ListSorted(ctx context.Context, filter rip.Filter, sort rip.Sort, offset, limit int) ([]Ent, error) {
+	ep.logger.Info("list sorted", "sort", sort, "offset", offset, "limit", limit)
+
+	var ee []Ent
+	if len(filter) > 0 {
+		return ee, errors.New("filters are not supported")
+	}
+
+	q := ep.db.Select(&ee)
+	for _, sf := range sort {
+		column, err := columnName[Ent](sf.Field)
+		if err != nil {
+			return ee, err
+		}
+
+		orderBy := ep.db.Adapter().Quote(column)
+		if sf.Descending {
+			orderBy += " DESC"
+		}
+		q = q.OrderBy(orderBy)
+	}
+
+	err := q.
+		Offset(offset).
+		Limit(limit).
+		Do()
+	if err != nil {
+		return ee, err
+	}
+
+	return ee, nil
+}
+
+// Count counts the entities.
+// The provider doesn't implement [rip.FilterLister], so filter is always empty.
+func (ep *godbEntityProvider[Ent]) Count(ctx context.Context, filter rip.Filter) (int, error) {
+	if len(filter) > 0 {
+		return 0, errors.New("filters are not supported")
+	}
+
+	var ee []Ent
+	count, err := ep.db.Select(&ee).Count()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(count), nil
+}
+
+// columnName finds the column name of the Ent field in its `db` struct tag.
+func columnName[Ent any](field string) (string, error) {
+	var e Ent
+	t := reflect.TypeOf(e)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	f, ok := t.FieldByName(field)
+	if !ok {
+		return "", fmt.Errorf("no field %q", field)
+	}
+
+	column, _, _ := strings.Cut(f.Tag.Get("db"), ",")
+	if column == "" {
+		return "", fmt.Errorf("no column for field %q", field)
+	}
+
+	return column, nil
+}
@@ -2,16 +2,26 @@ package gormprovider
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"log/slog"
 	"reflect"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/dolanor/rip"
 	"github.com/dolanor/rip/internal/ripreflect"
 )
 
+// New creates a provider storing the entities with gorm.
+// The entities with a nullable field (*time.Time or gorm.DeletedAt) with the `rip:"deleted_at"`
+// struct tag are soft deleted, and can be restored.
 func New[Ent any](db *gorm.DB, logger *slog.Logger) *gormEntityProvider[Ent] {
 	if logger == nil {
 		logger = slog.Default()
@@ -23,18 +33,60 @@ func New[Ent any](db *gorm.DB, logger *slog.Logger) *gormEntityProvider[Ent] {
 		panic("no ID field")
 	}
 
-	return &gormEntityProvider[Ent]{
+	ep := &gormEntityProvider[Ent]{
 		db:     db,
 		logger: logger,
 	}
+
+	_, deletedAt, ok := ripreflect.FindTaggedField(e, ripreflect.TagDeletedAt)
+	if ok {
+		column, err := ep.columnName(deletedAt.Name)
+		if err != nil {
+			panic(fmt.Sprintf("deleted_at field: %v", err))
+		}
+		ep.deletedAtColumn = column
+	}
+
+	return ep
 }
 
 type gormEntityProvider[Ent any] struct {
 	db *gorm.DB
 
+	// deletedAtColumn is the column of the deletion date of the soft deleted entities,
+	// if they are soft deleted.
+	deletedAtColumn string
+
 	logger *slog.Logger
 }
 
+// scope runs the queries with the request context, so they stop with it, and hides the soft
+// deleted entities, unless the request includes them.
+func (ep *gormEntityProvider[Ent]) scope(ctx context.Context) *gorm.DB {
+	db := ep.db.WithContext(ctx)
+	if ep.deletedAtColumn == "" {
+		return db
+	}
+
+	if rip.IncludeDeleted(ctx) {
+		// for the gorm.DeletedAt fields, gorm hides the deleted entities as well
+		return db.Unscoped()
+	}
+
+	return db.Where(clause.Eq{Column: clause.Column{Name: ep.deletedAtColumn}, Value: nil})
+}
+
+// whereID selects the entity with id.
+func (ep *gormEntityProvider[Ent]) whereID(tx *gorm.DB, id string) (*gorm.DB, error) {
+	var e Ent
+	column, err := ep.columnName(ripreflect.FieldIDName(e))
+	if err != nil {
+		return nil, err
+	}
+
+	return tx.Where(clause.Eq{Column: clause.Column{Name: column}, Value: id}), nil
+}
+
 func (ep *gormEntityProvider[Ent]) Create(ctx context.Context, e Ent) (Ent, error) {
 	id, err := ripreflect.GetID(e)
 	defer func() { ep.logger.Info("create", "entity", reflect.TypeOf(e).Name(), "id", id) }()
@@ -56,7 +108,7 @@ func (ep *gormEntityProvider[Ent]) Create(ctx context.Context, e Ent) (Ent, erro
 
 	}
 
-	res := ep.db.Create(&e)
+	res := ep.db.WithContext(ctx).Create(&e)
 	if res.Error != nil {
 		return e, res.Error
 	}
@@ -67,12 +119,26 @@ func (ep *gormEntityProvider[Ent]) Create(ctx context.Context, e Ent) (Ent, erro
 func (ep *gormEntityProvider[Ent]) Delete(ctx context.Context, id string) error {
 	var e Ent
 	ep.logger.Info("delete", "entity", reflect.TypeOf(e).Name(), "id", id)
+	if ep.deletedAtColumn != "" {
+		tx, err := ep.whereID(ep.scope(ctx).Model(&e), id)
+		if err != nil {
+			return err
+		}
+
+		tx = tx.Update(ep.deletedAtColumn, time.Now())
+		if tx.Error != nil {
+			return tx.Error
+		}
+
+		return nil
+	}
+
 	err := ripreflect.SetID(&e, id)
 	if err != nil {
 		return err
 	}
 
-	tx := ep.db.Delete(&e)
+	tx := ep.db.WithContext(ctx).Delete(&e)
 	if tx.Error != nil {
 		return tx.Error
 	}
@@ -80,6 +146,31 @@ func (ep *gormEntityProvider[Ent]) Delete(ctx context.Context, id string) error
 	return nil
 }
 
+// Restore restores the soft deleted entity with id.
+func (ep *gormEntityProvider[Ent]) Restore(ctx context.Context, id string) error {
+	var e Ent
+	ep.logger.Info("restore", "entity", reflect.TypeOf(e).Name(), "id", id)
+	if ep.deletedAtColumn == "" {
+		return errors.New("the entities are not soft deleted")
+	}
+
+	tx, err := ep.whereID(ep.db.WithContext(ctx).Unscoped().Model(&e), id)
+	if err != nil {
+		return err
+	}
+
+	tx = tx.Update(ep.deletedAtColumn, nil)
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if tx.RowsAffected == 0 {
+		return rip.ErrNotFound
+	}
+
+	return nil
+}
+
 func (ep *gormEntityProvider[Ent]) Update(ctx context.Context, e Ent) error {
 	id, err := ripreflect.GetID(e)
 	ep.logger.Info("update", "entity", reflect.TypeOf(e).Name(), "id", id)
@@ -87,7 +178,7 @@ func (ep *gormEntityProvider[Ent]) Update(ctx context.Context, e Ent) error {
 		return err
 	}
 
-	tx := ep.db.Save(&e)
+	tx := ep.db.WithContext(ctx).Save(&e)
 	if tx.Error != nil {
 		return tx.Error
 	}
@@ -107,7 +198,15 @@ func (ep *gormEntityProvider[Ent]) Get(ctx context.Context, id string) (Ent, err
 		return e, errors.New("no ID field")
 	}
 
-	tx := ep.db.First(&e)
+	tx, err := ep.whereID(ep.scope(ctx), id)
+	if err != nil {
+		return e, err
+	}
+
+	tx = tx.First(&e)
+	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+		return e, rip.ErrNotFound
+	}
 	if tx.Error != nil {
 		return e, tx.Error
 	}
@@ -122,7 +221,7 @@ func (ep *gormEntityProvider[Ent]) List(ctx context.Context, offset, limit int)
 		ep.logger.Info("list", "entity", reflect.TypeOf(e).Name(), "offset", offset, "limit", limit, "size", len(ee))
 	}()
 
-	tx := ep.db.
+	tx := ep.scope(ctx).
 		Offset(offset).
 		Limit(limit).
 		Find(&ee)
@@ -132,3 +231,182 @@ func (ep *gormEntityProvider[Ent]) List(ctx context.Context, offset, limit int)
 
 	return ee, nil
 }
+
+// ListSorted lists the entities ordered by sort.
+// The provider doesn't implement [rip.FilterLister], so filter is always empty.
+func (ep *gormEntityProvider[Ent]) ListSorted(ctx context.Context, filter rip.Filter, sort rip.Sort, offset, limit int) ([]Ent, error) {
+	var e Ent
+	var ee []Ent
+	defer func() {
+		ep.logger.Info("list sorted", "entity", reflect.TypeOf(e).Name(), "sort", sort, "offset", offset, "limit", limit, "size", len(ee))
+	}()
+
+	if len(filter) > 0 {
+		return ee, errors.New("filters are not supported")
+	}
+
+	tx := ep.scope(ctx)
+	for _, sf := range sort {
+		column, err := ep.columnName(sf.Field)
+		if err != nil {
+			return ee, err
+		}
+
+		tx = tx.Order(clause.OrderByColumn{
+			Column: clause.Column{Name: column},
+			Desc:   sf.Descending,
+		})
+	}
+
+	tx = tx.
+		Offset(offset).
+		Limit(limit).
+		Find(&ee)
+	if tx.Error != nil {
+		return ee, tx.Error
+	}
+
+	return ee, nil
+}
+
+// ListCursor lists the entities ordered by id, with keyset pagination: the cursors
+// point before the first or after the last id of the listed entities.
+func (ep *gormEntityProvider[Ent]) ListCursor(ctx context.Context, cursor string, limit int) (ee []Ent, next, prev string, err error) {
+	var e Ent
+	defer func() {
+		ep.logger.Info("list cursor", "entity", reflect.TypeOf(e).Name(), "cursor", cursor, "limit", limit, "size", len(ee))
+	}()
+
+	backward, id, err := decodeCursor(cursor)
+	if err != nil {
+		return ee, "", "", rip.ErrInvalidCursor
+	}
+
+	column, err := ep.columnName(ripreflect.FieldIDName(e))
+	if err != nil {
+		return ee, "", "", err
+	}
+
+	// we get one more entity to know if there is a next group
+	tx := ep.scope(ctx).Limit(limit + 1)
+	if cursor != "" {
+		if backward {
+			tx = tx.Where(clause.Lt{Column: clause.Column{Name: column}, Value: id})
+		} else {
+			tx = tx.Where(clause.Gt{Column: clause.Column{Name: column}, Value: id})
+		}
+	}
+
+	tx = tx.
+		Order(clause.OrderByColumn{
+			Column: clause.Column{Name: column},
+			Desc:   backward,
+		}).
+		Find(&ee)
+	if tx.Error != nil {
+		return ee, "", "", tx.Error
+	}
+
+	more := len(ee) > limit
+	if more {
+		ee = ee[:limit]
+	}
+
+	if backward {
+		slices.Reverse(ee)
+	}
+
+	if len(ee) == 0 {
+		// we went past the end (or the start) of the list, we can only go back
+		switch {
+		case cursor == "":
+		case backward:
+			next = encodeCursor(false, id)
+		default:
+			prev = encodeCursor(true, id)
+		}
+		return ee, next, prev, nil
+	}
+
+	firstID, err := ripreflect.GetID(ee[0])
+	if err != nil {
+		return ee, "", "", err
+	}
+
+	lastID, err := ripreflect.GetID(ee[len(ee)-1])
+	if err != nil {
+		return ee, "", "", err
+	}
+
+	if more || backward {
+		next = encodeCursor(false, lastID)
+	}
+
+	if (more && backward) || (!backward && cursor != "") {
+		prev = encodeCursor(true, firstID)
+	}
+
+	return ee, next, prev, nil
+}
+
+// Count counts the entities.
+// The provider doesn't implement [rip.FilterLister], so filter is always empty.
+func (ep *gormEntityProvider[Ent]) Count(ctx context.Context, filter rip.Filter) (int, error) {
+	var e Ent
+	if len(filter) > 0 {
+		return 0, errors.New("filters are not supported")
+	}
+
+	var count int64
+	tx := ep.scope(ctx).Model(&e).Count(&count)
+	if tx.Error != nil {
+		return 0, tx.Error
+	}
+
+	return int(count), nil
+}
+
+// columnName finds the column name of the Ent field.
+func (ep *gormEntityProvider[Ent]) columnName(field string) (string, error) {
+	var e Ent
+	stmt := &gorm.Statement{DB: ep.db}
+	err := stmt.Parse(&e)
+	if err != nil {
+		return "", err
+	}
+
+	f := stmt.Schema.LookUpField(field)
+	if f == nil || f.DBName == "" {
+		return "", fmt.Errorf("no column for field %q", field)
+	}
+
+	return f.DBName, nil
+}
+
+// encodeCursor creates an opaque cursor pointing after the id, or before it if backward.
+func encodeCursor(backward bool, id string) string {
+	direction := "n"
+	if backward {
+		direction = "p"
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(direction + ":" + id))
+}
+
+func decodeCursor(cursor string) (backward bool, id string, err error) {
+	if cursor == "" {
+		return false, "", nil
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return false, "", err
+	}
+
+	direction, id, ok := strings.Cut(string(b), ":")
+	if !ok || (direction != "n" && direction != "p") {
+		return false, "", errors.New("unknown cursor direction")
+	}
+
+	return direction == "p", id, nil
+}
@@ -7,6 +7,7 @@ import (
 	"slices"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -14,11 +15,18 @@ import (
 	"github.com/dolanor/rip/internal/ripreflect"
 )
 
+// New creates a provider storing the entities in a map.
+// The entities with a time.Time (or *time.Time) field with the `rip:"deleted_at"` struct tag
+// are soft deleted, and can be restored.
 func New[Ent any](logger *slog.Logger) *entityMapProvider[Ent] {
+	var e Ent
+	_, _, softDelete := ripreflect.FindTaggedField(e, ripreflect.TagDeletedAt)
+
 	return &entityMapProvider[Ent]{
-		store:     map[string]Ent{},
-		logger:    logger,
-		listCache: []Ent{},
+		store:      map[string]Ent{},
+		logger:     logger,
+		listCache:  []Ent{},
+		softDelete: softDelete,
 	}
 }
 
@@ -29,6 +37,9 @@ type entityMapProvider[Ent any] struct {
 
 	listCache []Ent
 
+	// softDelete tells if the entities are soft deleted.
+	softDelete bool
+
 	logger *slog.Logger
 }
 
@@ -66,7 +77,34 @@ func (dp *entityMapProvider[Ent]) Delete(ctx context.Context, id string) error {
 	dp.mu.Lock()
 	defer dp.mu.Unlock()
 
-	delete(dp.store, id)
+	e, ok := dp.store[id]
+	if !ok || ripreflect.IsDeleted(e) {
+		return nil
+	}
+
+	if dp.softDelete && ripreflect.SetTaggedTime(&e, ripreflect.TagDeletedAt, time.Now()) {
+		dp.store[id] = e
+	} else {
+		delete(dp.store, id)
+	}
+	dp.listCacheFresh = false
+
+	return nil
+}
+
+// Restore restores the soft deleted entity with id.
+func (dp *entityMapProvider[Ent]) Restore(ctx context.Context, id string) error {
+	dp.logger.Info("restore", "id", id)
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+
+	e, ok := dp.store[id]
+	if !ok {
+		return rip.ErrNotFound
+	}
+
+	ripreflect.ClearTaggedField(&e, ripreflect.TagDeletedAt)
+	dp.store[id] = e
 	dp.listCacheFresh = false
 
 	return nil
@@ -98,13 +136,18 @@ func (dp *entityMapProvider[Ent]) Get(ctx context.Context, id string) (Ent, erro
 
 	dp.logger.Info("get", "id", id)
 	e, ok := dp.store[id]
-	if !ok {
+	if !ok || hidden(ctx, e) {
 		return e, rip.ErrNotFound
 	}
 	return e, nil
 }
 
 func (dp *entityMapProvider[Ent]) List(ctx context.Context, offset, limit int) ([]Ent, error) {
+	if dp.softDelete {
+		// the cache can not hide the deleted entities
+		return dp.listQuery(ctx, nil, nil, offset, limit)
+	}
+
 	log := dp.logger.With("func", "list")
 	dp.mu.Lock()
 	defer dp.mu.Unlock()
@@ -128,34 +171,111 @@ func (dp *entityMapProvider[Ent]) List(ctx context.Context, offset, limit int) (
 		dd = append(dd, v)
 	}
 
-	slices.SortFunc(dd, func(a, b Ent) int {
-		idA, err := ripreflect.GetID(a)
-		if err != nil {
-			return -1
+	slices.SortFunc(dd, compareIDs)
+	dp.listCacheFresh = true
+	dp.listCache = dd
+
+	return dp.listCache[offset:limit], nil
+}
+
+// ListFiltered lists the entities matching filter, ordered by id.
+func (dp *entityMapProvider[Ent]) ListFiltered(ctx context.Context, filter rip.Filter, offset, limit int) ([]Ent, error) {
+	dp.logger.Info("list filtered", "filter", filter)
+	return dp.listQuery(ctx, filter, nil, offset, limit)
+}
+
+// ListSorted lists the entities matching filter, ordered by sort then by id.
+func (dp *entityMapProvider[Ent]) ListSorted(ctx context.Context, filter rip.Filter, sort rip.Sort, offset, limit int) ([]Ent, error) {
+	dp.logger.Info("list sorted", "filter", filter, "sort", sort)
+	return dp.listQuery(ctx, filter, sort, offset, limit)
+}
+
+func (dp *entityMapProvider[Ent]) listQuery(ctx context.Context, filter rip.Filter, sort rip.Sort, offset, limit int) ([]Ent, error) {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+
+	dd := []Ent{}
+	for _, v := range dp.store {
+		if hidden(ctx, v) {
+			continue
 		}
 
-		idB, err := ripreflect.GetID(b)
+		ok, err := filter.Match(v)
 		if err != nil {
-			return -1
+			return nil, err
 		}
 
-		comparison, ok := compareAsNumbers(idA, idB)
 		if ok {
-			return comparison
+			dd = append(dd, v)
 		}
+	}
 
-		if idA < idB {
-			return -1
-		} else if idA > idB {
-			return 1
+	slices.SortFunc(dd, func(a, b Ent) int {
+		c := sort.Compare(a, b)
+		if c != 0 {
+			return c
 		}
 
-		return 0
+		return compareIDs(a, b)
 	})
-	dp.listCacheFresh = true
-	dp.listCache = dd
 
-	return dp.listCache[offset:limit], nil
+	offset = min(offset, len(dd))
+	end := min(offset+limit, len(dd))
+	return dd[offset:end], nil
+}
+
+// Count counts the entities matching filter.
+func (dp *entityMapProvider[Ent]) Count(ctx context.Context, filter rip.Filter) (int, error) {
+	dp.mu.Lock()
+	defer dp.mu.Unlock()
+
+	count := 0
+	for _, v := range dp.store {
+		if hidden(ctx, v) {
+			continue
+		}
+
+		ok, err := filter.Match(v)
+		if err != nil {
+			return 0, err
+		}
+
+		if ok {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// hidden tells if e is soft deleted, and hidden from the request.
+func hidden[Ent any](ctx context.Context, e Ent) bool {
+	return !rip.IncludeDeleted(ctx) && ripreflect.IsDeleted(e)
+}
+
+func compareIDs[Ent any](a, b Ent) int {
+	idA, err := ripreflect.GetID(a)
+	if err != nil {
+		return -1
+	}
+
+	idB, err := ripreflect.GetID(b)
+	if err != nil {
+		return -1
+	}
+
+	comparison, ok := compareAsNumbers(idA, idB)
+	if ok {
+		return comparison
+	}
+
+	if idA < idB {
+		return -1
+	} else if idA > idB {
+		return 1
+	}
+
+	return 0
 }
 
 func compareAsNumbers(idA, idB string) (comparison int, ok bool) {
@@ -15,7 +15,7 @@ func preprocessRequest(reqMethod, handlerMethod string, header http.Header, urlP
 	}
 
 	if accept == "" &&
-		(reqMethod == http.MethodPost || reqMethod == http.MethodGet) {
+		(reqMethod == http.MethodPost || reqMethod == http.MethodGet || reqMethod == http.MethodHead) {
 		return "", "", "", Error{
 			Status: http.StatusNotAcceptable,
 			Detail: fmt.Sprintf("bad accept type: %v, codecs available: %v", header["Accept"], cfg.codecs.OrderedMimeTypes),
@@ -26,7 +26,7 @@ func preprocessRequest(reqMethod, handlerMethod string, header http.Header, urlP
 		return "", "", "", Error{Status: http.StatusMethodNotAllowed, Detail: "bad method"}
 	}
 
-	if reqMethod == http.MethodGet {
+	if reqMethod == http.MethodGet || reqMethod == http.MethodHead {
 		// We can ignore content type as there should be no body for a GET
 		return urlPath, accept, contentType, nil
 	}
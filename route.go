@@ -1,11 +1,15 @@
 package rip
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"path"
+	"reflect"
+	"slices"
+	"strings"
 
 	ripjson "github.com/dolanor/rip/encoding/json"
 	"github.com/dolanor/rip/internal/ripreflect"
@@ -30,6 +34,8 @@ type EntityRoute[
 
 	openAPISchema *openapi3.T
 	generator     *openapi3gen.Generator
+
+	cfg entityRouteConfig
 }
 
 func (er *EntityRoute[Ent, EP]) Path() string {
@@ -59,6 +65,7 @@ func NewEntityRoute[
 
 	generator := openapi3gen.NewGenerator(
 		openapi3gen.UseAllExportedFields(),
+		openapi3gen.SchemaCustomizer(fieldSchemaCustomizer),
 	)
 
 	// just a base that we can merge with other entity routes on the router
@@ -68,9 +75,11 @@ func NewEntityRoute[
 		provider:      entityProvider,
 		openAPISchema: &oaSpec,
 		generator:     generator,
+		cfg:           cfg,
 	}
 
 	rt.generateOperation()
+	rt.generateNestedRoutes()
 
 	_, handler := rt.createEntityHandler(path, entityProvider, cfg)
 	rt.handlerFunc = handler
@@ -89,7 +98,59 @@ func (rt *EntityRoute[Ent, EP]) createEntityHandler(
 
 	cfg = setEntityRouteConfigDefaults(cfg)
 
-	return handleEntityWithPath(urlPath, ep.Create, ep.Get, ep.Update, ep.Delete, ep.List, cfg)
+	return handleEntityWithPath[Ent](urlPath, ep, cfg)
+}
+
+func (rt *EntityRoute[Ent, EP]) nestedHandler() http.HandlerFunc {
+	return rt.handlerFunc
+}
+
+func (rt *EntityRoute[Ent, EP]) entityType() reflect.Type {
+	return reflect.TypeFor[Ent]()
+}
+
+func (rt *EntityRoute[Ent, EP]) getEntity(ctx context.Context, id string) (any, error) {
+	ent, err := rt.provider.Get(ctx, id)
+	if err != nil {
+		return nil, toError(err, rt.cfg)
+	}
+
+	return ent, nil
+}
+
+// generateNestedRoutes adds the paths of the nested routes under the entity path,
+// e.g. /users/{user_id}/albums/{id}
+func (rt *EntityRoute[Ent, EP]) generateNestedRoutes() {
+	var ent Ent
+	tag, ok := ripreflect.TagFromType(ent)
+	if !ok {
+		panic("generate OpenAPI operation: can not get type tag")
+	}
+
+	// the nested route already has an {id} path parameter
+	paramName := strings.ToLower(tag) + "_id"
+	param := openapi3.NewPathParameter(paramName)
+	param.Description = "id of the parent " + tag
+	param.Schema = openapi3.NewStringSchema().NewRef()
+
+	for _, nested := range rt.cfg.nestedRoutes {
+		for k, v := range nested.OpenAPISchema().Components.Schemas {
+			rt.openAPISchema.Components.Schemas[k] = v
+		}
+
+		for p, item := range nested.OpenAPISchema().Paths.Map() {
+			nestedPath := path.Join(rt.path, "{"+paramName+"}", p)
+			// the list path of the nested route is a collection
+			if p == nested.Path() || strings.HasSuffix(p, "/") {
+				nestedPath += "/"
+			}
+
+			// the nested route path item can be mounted on several routes
+			nestedItem := *item
+			nestedItem.Parameters = append(slices.Clone(item.Parameters), &openapi3.ParameterRef{Value: param})
+			rt.openAPISchema.Paths.Set(nestedPath, &nestedItem)
+		}
+	}
 }
 
 func (rt *EntityRoute[Ent, EP]) generateOperation() {
@@ -121,11 +182,12 @@ func (rt *EntityRoute[Ent, EP]) generateOperation() {
 		http.MethodPost,
 		http.MethodGet,
 		http.MethodPut,
+		http.MethodPatch,
 		http.MethodDelete,
 	} {
 		op := openapi3.NewOperation()
 		op.Tags = append(op.Tags, tag)
-		if (method == http.MethodPost || method == http.MethodPut) && tag != "string" {
+		if (method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch) && tag != "string" {
 			bodySchema, ok := rt.openAPISchema.Components.Schemas[tag]
 			if !ok {
 				var err error
@@ -144,8 +206,16 @@ func (rt *EntityRoute[Ent, EP]) generateOperation() {
 
 			if bodySchema != nil {
 				// TODO add route options multiple encoding
-				content := openapi3.NewContentWithSchema(bodySchema.Value, []string{"application/json"})
-				content["application/json"].Schema.Ref = "#/components/schemas/" + tag
+				mimeType := "application/json"
+				if method == http.MethodPatch {
+					// a merge patch document has the same shape as the entity
+					mimeType = MergePatchMimeType
+				}
+				content := openapi3.NewContentWithSchema(bodySchema.Value, []string{mimeType})
+				content[mimeType].Schema.Ref = "#/components/schemas/" + tag
+				if method == http.MethodPatch {
+					content[JSONPatchMimeType] = openapi3.NewMediaType().WithSchema(jsonPatchSchema())
+				}
 				requestBody.WithContent(content)
 			}
 
@@ -186,6 +256,7 @@ func (rt *EntityRoute[Ent, EP]) generateOperation() {
 		switch method {
 		case http.MethodGet,
 			http.MethodPut,
+			http.MethodPatch,
 			http.MethodDelete:
 			entityPath = path.Join(rt.path, "{id}")
 		}
@@ -236,6 +307,18 @@ func (rt *EntityRoute[Ent, EP]) generateList() {
 	rt.openAPISchema.AddOperation(entityPath, method, op)
 }
 
+// jsonPatchSchema describes a JSON Patch document (RFC 6902).
+func jsonPatchSchema() *openapi3.Schema {
+	operation := openapi3.NewObjectSchema().
+		WithProperty("op", openapi3.NewStringSchema().WithEnum("add", "remove", "replace", "move", "copy", "test")).
+		WithProperty("path", openapi3.NewStringSchema()).
+		WithProperty("from", openapi3.NewStringSchema()).
+		WithProperty("value", openapi3.NewSchema())
+	operation.Required = []string{"op", "path"}
+
+	return openapi3.NewArraySchema().WithItems(operation)
+}
+
 func dumpSchema(title string, schema any) {
 	b, _ := json.Marshal(schema)
 	fmt.Print(string(b))
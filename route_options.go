@@ -2,6 +2,7 @@ package rip
 
 import (
 	"log/slog"
+	"time"
 
 	"github.com/dolanor/rip/encoding"
 )
@@ -15,6 +16,24 @@ type entityRouteConfig struct {
 	statusMap       StatusMap
 	listPageSize    int
 	listPageSizeMax int
+
+	preconditionRequired bool
+	preferReturn         ReturnPreference
+	upsert               bool
+
+	nestedRoutes map[string]NestedRoute
+	references   map[string]ReferencedRoute
+
+	idempotency *idempotency
+
+	maxBodySize    int64
+	strictDecoding bool
+
+	routeTimeout time.Duration
+	timeouts     map[EntityOperation]time.Duration
+
+	operationStore OperationStore
+	operationTTL   time.Duration
 }
 
 // EntityRouteOption is the optional configuration for a [EntityRoute].
@@ -63,3 +82,154 @@ func WithMiddlewares(middlewares ...Middleware) EntityRouteOption {
 		}
 	}
 }
+
+// WithPreconditionRequired makes updates and deletes on this route conditional:
+// they must send an If-Match header with the entity ETag, otherwise they get
+// a 428 Precondition Required error.
+// It prevents clients from overwriting changes they haven't seen.
+func WithPreconditionRequired() EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		cfg.preconditionRequired = true
+	}
+}
+
+// WithPreferReturn lets clients choose the response to an entity creation with the
+// `Prefer: return=minimal` or `Prefer: return=representation` request header (RFC 7240).
+// defaultReturn is used when the client doesn't send any preference.
+func WithPreferReturn(defaultReturn ReturnPreference) EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		cfg.preferReturn = defaultReturn
+	}
+}
+
+// WithUpsert makes PUT requests create-or-replace the entity: if the entity doesn't exist yet,
+// it is created with the ID from the request path and the route answers 201 Created.
+// It allows idempotent provisioning of entities with client chosen IDs.
+func WithUpsert() EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		cfg.upsert = true
+	}
+}
+
+// WithNestedRoute mounts the route under every entity of this route, as a sub-resource:
+//
+//	rip.NewEntityRoute("/users/", up, rip.WithNestedRoute(rip.NewEntityRoute("albums", ap, …)))
+//
+// handles the albums of the user 42 on /users/42/albums/.
+// The parent entity must exist, otherwise the request fails with 404 Not Found.
+// The nested route provider gets the parent id with [ParentID].
+func WithNestedRoute(route NestedRoute) EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		if cfg.nestedRoutes == nil {
+			cfg.nestedRoutes = map[string]NestedRoute{}
+		}
+
+		cfg.nestedRoutes[nestedRouteName(route)] = route
+	}
+}
+
+// WithReferences lets the entities of this route reference the entities of routes,
+// with a `rip:"ref=…"` struct tag on the field holding the referenced entity id:
+//
+//	type Track struct {
+//		ID      string
+//		AlbumID string `json:"album_id" rip:"ref=albums"`
+//	}
+//
+//	rip.NewEntityRoute("/tracks/", tp, rip.WithReferences(albumsRoute))
+//
+// The referenced entities are then included in the responses with the include query parameter:
+//
+//	GET /tracks/1?include=album
+func WithReferences(routes ...ReferencedRoute) EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		if cfg.references == nil {
+			cfg.references = map[string]ReferencedRoute{}
+		}
+
+		for _, route := range routes {
+			cfg.references[referenceName(route)] = route
+		}
+	}
+}
+
+// WithIdempotency makes the POST and PATCH requests with an Idempotency-Key header idempotent:
+// the first response with a key is stored in store for ttl, and replayed to the requests with
+// the same key, e.g. the retries of a client after a network timeout.
+// A key reused for another request gets a 422 Unprocessable Entity error.
+// It can also be used with [Handle] and [HandleAsync], for the requests with their method.
+//
+// store defaults to a [MemoryIdempotencyStore], and ttl to 24 hours.
+func WithIdempotency(store IdempotencyStore, ttl time.Duration) EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		if store == nil {
+			store = NewMemoryIdempotencyStore()
+		}
+
+		if ttl <= 0 {
+			ttl = defaultIdempotencyTTL
+		}
+
+		cfg.idempotency = &idempotency{
+			store: store,
+			ttl:   ttl,
+		}
+	}
+}
+
+// WithMaxBodySize limits the size of the request bodies to limit bytes: a larger body gets
+// a 413 Content Too Large error.
+// It can also be used with [Handle].
+func WithMaxBodySize(limit int64) EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		cfg.maxBodySize = limit
+	}
+}
+
+// WithStrictDecoding rejects the request bodies with unknown fields, or with data after the
+// decoded value, with a 400 Bad Request error pointing to the unknown field.
+// It only applies to the codecs with a strict decoder, like the JSON, XML and YAML codecs.
+// It can also be used with [Handle].
+func WithStrictDecoding() EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		cfg.strictDecoding = true
+	}
+}
+
+// WithTimeout sets the deadline of the operations ops on this route, or of all of them without ops.
+// The deadline is on the context given to the provider, which should stop once it is exceeded:
+// the request then fails with a 504 Gateway Timeout error.
+// The timeout of an operation takes precedence over the timeout of the route, so
+//
+//	rip.WithTimeout(time.Second), rip.WithTimeout(10*time.Second, rip.EntityList)
+//
+// gives 10 seconds to list the entities, and 1 second to the other operations.
+// It can also be used with [Handle], whose operation depends on its method: e.g. POST is [EntityCreate].
+func WithTimeout(timeout time.Duration, ops ...EntityOperation) EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		if len(ops) == 0 {
+			cfg.routeTimeout = timeout
+			return
+		}
+
+		if cfg.timeouts == nil {
+			cfg.timeouts = map[EntityOperation]time.Duration{}
+		}
+
+		for _, op := range ops {
+			cfg.timeouts[op] = timeout
+		}
+	}
+}
+
+// WithOperationStore configures the store of the operations started by [HandleAsync]: an
+// operation is kept in store for ttl after it is started, then after it is finished.
+// The operations can only be canceled on the instance running them.
+//
+// store defaults to a [MemoryOperationStore], and ttl to 24 hours.
+func WithOperationStore(store OperationStore, ttl time.Duration) EntityRouteOption {
+	return func(cfg *entityRouteConfig) {
+		cfg.operationStore = store
+		cfg.operationTTL = ttl
+	}
+}
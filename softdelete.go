@@ -0,0 +1,154 @@
+package rip
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+
+	"github.com/dolanor/rip/encoding"
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// restorePathField is the path, after the entity id, of the restore endpoint:
+// POST /entities/:id/_restore
+const restorePathField = "_restore"
+
+// isRestorePath tells if requestPath is the restore endpoint of an entity of the route.
+func isRestorePath(urlPath, requestPath string) bool {
+	id, field := getEntityField(urlPath, requestPath)
+	return id != "" && id != batchPathID && field == restorePathField
+}
+
+// Restorer can be implemented by an [EntityProvider] of soft deletable entities, the ones
+// with a `rip:"deleted_at"` field, for the restore endpoint:
+//
+//	POST /entities/:id/_restore
+//
+// The provider soft deletes the entities in Delete, by setting their deleted_at field,
+// and hides them from Get and the lists, unless [IncludeDeleted] is true.
+type Restorer interface {
+	// Restore restores the soft deleted entity with id, by resetting its deleted_at field.
+	// It returns [ErrNotFound] if the entity doesn't exist.
+	Restore(ctx context.Context, id string) error
+}
+
+type restoreFunc func(ctx context.Context, id string) error
+
+type includeDeletedKey struct{}
+
+// IncludeDeleted tells if the soft deleted entities are included in the response,
+// with the include_deleted query parameter:
+//
+//	GET /entities/?include_deleted=true
+func IncludeDeleted(ctx context.Context) bool {
+	include, _ := ctx.Value(includeDeletedKey{}).(bool)
+	return include
+}
+
+func withIncludeDeleted(ctx context.Context) context.Context {
+	return context.WithValue(ctx, includeDeletedKey{}, true)
+}
+
+// softDeletable tells if the entities of type Ent have a `rip:"deleted_at"` field.
+func softDeletable[Ent any]() bool {
+	t, ok := ripreflect.StructType(reflect.TypeFor[Ent]())
+	if !ok {
+		return false
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() && ripreflect.HasRIPTag(f, ripreflect.TagDeletedAt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// entityRestore returns the restore function of the entity provider, or nil if its entities
+// can not be restored.
+func entityRestore[Ent any](ep EntityProvider[Ent]) restoreFunc {
+	r, ok := ep.(Restorer)
+	if !ok || !softDeletable[Ent]() {
+		return nil
+	}
+
+	return r.Restore
+}
+
+// parseIncludeDeleted parses the include_deleted query parameter.
+func parseIncludeDeleted[Ent any](query url.Values) (bool, error) {
+	if !query.Has("include_deleted") {
+		return false, nil
+	}
+
+	include, err := strconv.ParseBool(query.Get("include_deleted"))
+	if err != nil {
+		return false, Error{
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf("invalid include_deleted value %q, it must be a boolean", query.Get("include_deleted")),
+			Source: ErrorSource{
+				Parameter: "include_deleted",
+			},
+		}
+	}
+
+	if !softDeletable[Ent]() {
+		return false, Error{
+			Status: http.StatusBadRequest,
+			Detail: "this entity can not be soft deleted",
+			Source: ErrorSource{
+				Parameter: "include_deleted",
+			},
+		}
+	}
+
+	return include, nil
+}
+
+// handleRestore restores a soft deleted entity, and answers with it.
+func handleRestore[Ent any](urlPath, method string, restore restoreFunc, get getFunc[Ent], etag etagFunc[Ent], cfg entityRouteConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cleanedPath, accept, _, err := preprocessRequest(r.Method, method, r.Header, r.URL.Path, cfg)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+
+		id, _ := getEntityField(urlPath, cleanedPath)
+
+		err = restore(r.Context(), id)
+		if err != nil {
+			writeError(w, accept, fmt.Errorf("entity provider restore: %w", err), cfg)
+			return
+		}
+
+		ent, err := get(r.Context(), id)
+		if err != nil {
+			writeError(w, accept, fmt.Errorf("can not get restored entity: %w", err), cfg)
+			return
+		}
+
+		err = setETag(w, r, accept, ent, etag)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+		setLastModified(w, ent)
+
+		rrw := encoding.RequestResponseWriter{
+			ResponseWriter: w,
+			Request:        r,
+		}
+
+		err = acceptEncoder(rrw, accept, encoding.EditOff, cfg).Encode(ent)
+		if err != nil {
+			writeError(w, accept, err, cfg)
+			return
+		}
+	}
+}
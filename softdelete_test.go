@@ -0,0 +1,179 @@
+package rip_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dolanor/rip"
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+	"github.com/dolanor/rip/providers/mapprovider"
+)
+
+type invoice struct {
+	ID        string     `json:"id"`
+	Amount    int        `json:"amount"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty" rip:"deleted_at"`
+}
+
+func TestSoftDelete(t *testing.T) {
+	ip := mapprovider.New[invoice](slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, id := range []string{"1", "2"} {
+		_, err := ip.Create(context.Background(), invoice{ID: id, Amount: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(rip.HandleEntities("/invoices/", ip, rip.WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	do := func(t *testing.T, method, path, body string) (int, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := s.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return resp.StatusCode, string(b)
+	}
+
+	list := func(t *testing.T, path string) []invoice {
+		t.Helper()
+
+		status, body := do(t, http.MethodGet, path, "")
+		if status != http.StatusOK {
+			t.Fatal("list status code is not 200:", status, body)
+		}
+
+		var invoices []invoice
+		err := json.Unmarshal([]byte(body), &invoices)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return invoices
+	}
+
+	status, _ := do(t, http.MethodDelete, "/invoices/1", "")
+	if status != http.StatusNoContent {
+		t.Fatal("delete status code is not 204:", status)
+	}
+
+	t.Run("get", func(t *testing.T) {
+		status, _ := do(t, http.MethodGet, "/invoices/1", "")
+		if status != http.StatusNotFound {
+			t.Fatal("status code is not 404:", status)
+		}
+
+		status, body := do(t, http.MethodGet, "/invoices/1?include_deleted=true", "")
+		if status != http.StatusOK || !strings.Contains(body, "deleted_at") {
+			t.Fatal("the deleted entity is not included:", status, body)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		invoices := list(t, "/invoices/")
+		if len(invoices) != 1 || invoices[0].ID != "2" {
+			t.Fatal("the deleted entity is listed:", invoices)
+		}
+
+		invoices = list(t, "/invoices/?include_deleted=true")
+		if len(invoices) != 2 || invoices[0].DeletedAt == nil {
+			t.Fatal("the deleted entity is not listed:", invoices)
+		}
+	})
+
+	t.Run("update deleted_at", func(t *testing.T) {
+		status, body := do(t, http.MethodPut, "/invoices/2", `{"amount": 20, "deleted_at": "2024-01-01T00:00:00Z"}`)
+		if status != http.StatusOK {
+			t.Fatal("status code is not 200:", status, body)
+		}
+
+		if len(list(t, "/invoices/")) != 1 {
+			t.Fatal("a client soft deleted the entity")
+		}
+	})
+
+	t.Run("bad include_deleted", func(t *testing.T) {
+		status, _ := do(t, http.MethodGet, "/invoices/?include_deleted=maybe", "")
+		if status != http.StatusBadRequest {
+			t.Fatal("status code is not 400:", status)
+		}
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		status, body := do(t, http.MethodPost, "/invoices/1/_restore", "")
+		if status != http.StatusOK {
+			t.Fatal("status code is not 200:", status, body)
+		}
+
+		var inv invoice
+		err := json.Unmarshal([]byte(body), &inv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inv.ID != "1" || inv.DeletedAt != nil {
+			t.Fatal("bad restored entity:", inv)
+		}
+
+		if len(list(t, "/invoices/")) != 2 {
+			t.Fatal("the restored entity is not listed")
+		}
+	})
+
+	t.Run("restore unknown", func(t *testing.T) {
+		status, _ := do(t, http.MethodPost, "/invoices/42/_restore", "")
+		if status != http.StatusNotFound {
+			t.Fatal("status code is not 404:", status)
+		}
+	})
+
+	t.Run("not soft deletable", func(t *testing.T) {
+		type receipt struct {
+			ID string `json:"id"`
+		}
+
+		rp := mapprovider.New[receipt](slog.New(slog.NewTextHandler(io.Discard, nil)))
+		_, handler := rip.HandleEntities("/receipts/", rp, rip.WithCodecs(jsoncodec.Codec))
+
+		for path, status := range map[string]int{
+			"/receipts/?include_deleted=true": http.StatusBadRequest,
+			"/receipts/1/_restore":            http.StatusMethodNotAllowed,
+		} {
+			method := http.MethodGet
+			if strings.HasSuffix(path, "_restore") {
+				method = http.MethodPost
+			}
+
+			req := httptest.NewRequest(method, path, nil)
+			req.Header.Set("Accept", "application/json")
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != status {
+				t.Fatalf("%s: status code is not %d: %d", path, status, rec.Code)
+			}
+		}
+	})
+}
@@ -0,0 +1,105 @@
+package rip
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// SortField is a field to order the listed entities by.
+type SortField struct {
+	// Field is the name of the entity struct field, e.g. "CreatedAt".
+	Field string
+
+	// Descending orders the entities from the greatest field value to the lowest.
+	Descending bool
+}
+
+// Sort orders the listed entities by its fields: the entities with equal values for the
+// first field are ordered by the second one, and so on.
+type Sort []SortField
+
+// Compare orders a and b by the sort fields.
+// It can be used by an entity provider sorting its entities in memory.
+// It returns -1 if a is before b, 1 if a is after b, and 0 if the order is not decided.
+func (s Sort) Compare(a, b any) int {
+	for _, sf := range s {
+		fa, okA := ripreflect.StructField(a, sf.Field)
+		fb, okB := ripreflect.StructField(b, sf.Field)
+		if !okA || !okB {
+			continue
+		}
+
+		c := ripreflect.Compare(fa, fb)
+		if sf.Descending {
+			c = -c
+		}
+
+		if c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
+
+// parseSort parses the sort query parameter of a list query, e.g.:
+//
+//	?sort=-created,name
+//
+// sorts the entities by descending creation date, then by name.
+// The fields are looked up in Ent like the field filters.
+func parseSort[Ent any](query url.Values) (Sort, error) {
+	var ent Ent
+	entType := reflect.TypeOf(ent)
+
+	sortErr := func(format string, args ...any) error {
+		return Error{
+			Status: http.StatusBadRequest,
+			Detail: fmt.Sprintf(format, args...),
+			Source: ErrorSource{
+				Parameter: "sort",
+			},
+		}
+	}
+
+	var sort Sort
+	for _, v := range query["sort"] {
+		if v == "" {
+			continue
+		}
+
+		for _, name := range strings.Split(v, ",") {
+			var sf SortField
+			// an unescaped "+name" in the query string is decoded as " name"
+			name = strings.TrimSpace(name)
+			name, sf.Descending = strings.CutPrefix(name, "-")
+			if !sf.Descending {
+				name = strings.TrimPrefix(name, "+")
+			}
+
+			field, ok := ripreflect.FieldByName(entType, name)
+			if !ok {
+				return nil, sortErr("unknown field %q", name)
+			}
+
+			if isWriteOnly(field) {
+				// the order would reveal the hidden values
+				return nil, sortErr("field %q is write-only", name)
+			}
+
+			if !ripreflect.IsComparable(field.Type) {
+				return nil, sortErr("field %q can not be sorted", name)
+			}
+
+			sf.Field = field.Name
+			sort = append(sort, sf)
+		}
+	}
+
+	return sort, nil
+}
@@ -0,0 +1,99 @@
+package rip
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+// sortMemoryProvider filters and sorts its entities in memory.
+type sortMemoryProvider[Ent any] struct {
+	filterMemoryProvider[Ent]
+}
+
+func (sp sortMemoryProvider[Ent]) ListSorted(ctx context.Context, filter Filter, sort Sort, offset, limit int) ([]Ent, error) {
+	ents, err := sp.ListFiltered(ctx, filter, 0, len(sp.mem))
+	if err != nil {
+		return nil, err
+	}
+
+	// the filtered entities are ordered by ID, keep it for equal values
+	slices.SortStableFunc(ents, func(a, b Ent) int {
+		return sort.Compare(a, b)
+	})
+
+	offset = min(offset, len(ents))
+	return ents[offset:min(offset+limit, len(ents))], nil
+}
+
+func TestListSort(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/products/", sortMemoryProvider[product]{filterMemoryProvider[product]{newProductProvider()}}, WithCodecs(jsoncodec.Codec)))
+	mux.HandleFunc(HandleEntities("/unsorted/", filterMemoryProvider[product]{newProductProvider()}, WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	// products ordered by name: chair (1), lamp (3), sofa (4), table (2)
+	cases := map[string]struct {
+		path   string
+		query  string
+		status int
+		ids    []string
+	}{
+		"ascending":        {query: "?sort=price", status: http.StatusOK, ids: []string{"3", "1", "2", "4"}},
+		"descending":       {query: "?sort=-price", status: http.StatusOK, ids: []string{"4", "2", "1", "3"}},
+		"explicit":         {query: "?sort=%2Bprice", status: http.StatusOK, ids: []string{"3", "1", "2", "4"}},
+		"several fields":   {query: "?sort=status,-name", status: http.StatusOK, ids: []string{"3", "1", "4", "2"}},
+		"with filter":      {query: "?sort=-name&status=available", status: http.StatusOK, ids: []string{"3", "1"}},
+		"with page":        {query: "?sort=name&page=2&page_size=2", status: http.StatusOK, ids: []string{"4", "2"}},
+		"unknown field":    {query: "?sort=color", status: http.StatusBadRequest},
+		"write-only field": {query: "?sort=cost", status: http.StatusBadRequest},
+		"empty field":      {query: "?sort=name,", status: http.StatusBadRequest},
+		"sort unsupported": {path: "/unsorted/", query: "?sort=name", status: http.StatusBadRequest},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			path := "/products/"
+			if c.path != "" {
+				path = c.path
+			}
+
+			resp, err := s.Client().Get(s.URL + path + c.query)
+			panicErr(t, err)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != c.status {
+				t.Fatalf("status code is not %d: %d", c.status, resp.StatusCode)
+			}
+
+			if c.status != http.StatusOK {
+				var e Error
+				err = json.NewDecoder(resp.Body).Decode(&e)
+				panicErr(t, err)
+				if e.Source.Parameter != "sort" {
+					t.Fatalf("error parameter is not sort: %q", e.Source.Parameter)
+				}
+				return
+			}
+
+			var products []product
+			err = json.NewDecoder(resp.Body).Decode(&products)
+			panicErr(t, err)
+
+			ids := []string{}
+			for _, p := range products {
+				ids = append(ids, p.ID)
+			}
+			if !reflect.DeepEqual(ids, c.ids) {
+				t.Fatalf("listed ids are not %v: %v", c.ids, ids)
+			}
+		})
+	}
+}
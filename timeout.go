@@ -0,0 +1,83 @@
+package rip
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// EntityOperation is an operation of an entity route, see [WithTimeout].
+type EntityOperation string
+
+const (
+	// EntityGet gets an entity, or one of its fields: GET and HEAD /entities/:id.
+	EntityGet EntityOperation = "get"
+	// EntityList lists the entities: GET and HEAD /entities/.
+	EntityList EntityOperation = "list"
+	// EntityCreate creates an entity, or a batch of entities: POST /entities/.
+	EntityCreate EntityOperation = "create"
+	// EntityUpdate updates or restores an entity: PUT, PATCH /entities/:id and POST /entities/:id/_restore.
+	EntityUpdate EntityOperation = "update"
+	// EntityDelete deletes an entity: DELETE /entities/:id.
+	EntityDelete EntityOperation = "delete"
+)
+
+// entityOperation finds the operation of the request r on the route at urlPath, if it has one.
+func entityOperation(urlPath string, r *http.Request) EntityOperation {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if r.URL.Path == urlPath {
+			return EntityList
+		}
+		return EntityGet
+	case http.MethodPost:
+		if isRestorePath(urlPath, r.URL.Path) {
+			return EntityUpdate
+		}
+		return EntityCreate
+	case http.MethodPut, http.MethodPatch:
+		return EntityUpdate
+	case http.MethodDelete:
+		return EntityDelete
+	default:
+		return ""
+	}
+}
+
+// methodOperation finds the operation of a [Handle] function handling the method, if it has one.
+func methodOperation(method string) EntityOperation {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return EntityGet
+	case http.MethodPost:
+		return EntityCreate
+	case http.MethodPut, http.MethodPatch:
+		return EntityUpdate
+	case http.MethodDelete:
+		return EntityDelete
+	default:
+		return ""
+	}
+}
+
+// timeout returns the timeout of the operation op, 0 if there is none.
+func (cfg entityRouteConfig) timeout(op EntityOperation) time.Duration {
+	timeout, ok := cfg.timeouts[op]
+	if ok {
+		return timeout
+	}
+
+	return cfg.routeTimeout
+}
+
+// withTimeout sets the deadline of the operation op on the request context.
+// The cancel function must be called once the request is handled.
+func withTimeout(r *http.Request, op EntityOperation, cfg entityRouteConfig) (*http.Request, context.CancelFunc) {
+	timeout := cfg.timeout(op)
+	if op == "" || timeout <= 0 {
+		return r, func() {}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return r.WithContext(ctx), cancel
+}
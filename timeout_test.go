@@ -0,0 +1,104 @@
+package rip
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+type report struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// slowGetMemoryProvider takes delay to get an entity, unless its context is done before.
+type slowGetMemoryProvider[Ent any] struct {
+	*memoryProvider[Ent]
+
+	delay time.Duration
+}
+
+func (sp slowGetMemoryProvider[Ent]) Get(ctx context.Context, id string) (Ent, error) {
+	select {
+	case <-time.After(sp.delay):
+		return sp.memoryProvider.Get(ctx, id)
+	case <-ctx.Done():
+		var e Ent
+		return e, ctx.Err()
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	rp := slowGetMemoryProvider[report]{
+		memoryProvider: newMemoryProvider(report{ID: "1", Title: "sales"}),
+		delay:          100 * time.Millisecond,
+	}
+
+	do := func(t *testing.T, handler http.HandlerFunc, method, path string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"id": "1"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json")
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+
+		return rec
+	}
+
+	t.Run("get", func(t *testing.T) {
+		_, handler := HandleEntities("/reports/", rp, WithCodecs(jsoncodec.Codec), WithTimeout(10*time.Millisecond, EntityGet))
+
+		rec := do(t, handler, http.MethodGet, "/reports/1")
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Fatal("status code is not 504:", rec.Code, rec.Body)
+		}
+
+		// the other operations have no deadline
+		rec = do(t, handler, http.MethodGet, "/reports/")
+		if rec.Code != http.StatusOK {
+			t.Fatal("status code is not 200:", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("route", func(t *testing.T) {
+		_, handler := HandleEntities("/reports/", rp, WithCodecs(jsoncodec.Codec), WithTimeout(10*time.Millisecond))
+
+		rec := do(t, handler, http.MethodGet, "/reports/1")
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Fatal("status code is not 504:", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("operation precedence", func(t *testing.T) {
+		_, handler := HandleEntities("/reports/", rp, WithCodecs(jsoncodec.Codec), WithTimeout(time.Second, EntityGet), WithTimeout(10*time.Millisecond))
+
+		rec := do(t, handler, http.MethodGet, "/reports/1")
+		if rec.Code != http.StatusOK {
+			t.Fatal("status code is not 200:", rec.Code, rec.Body)
+		}
+	})
+
+	t.Run("handle", func(t *testing.T) {
+		generate := func(ctx context.Context, r report) (report, error) {
+			select {
+			case <-time.After(time.Second):
+				return r, nil
+			case <-ctx.Done():
+				return r, ctx.Err()
+			}
+		}
+
+		handler := Handle(http.MethodPost, generate, WithCodecs(jsoncodec.Codec), WithTimeout(10*time.Millisecond, EntityCreate))
+
+		rec := do(t, handler, http.MethodPost, "/generate")
+		if rec.Code != http.StatusGatewayTimeout {
+			t.Fatal("status code is not 504:", rec.Code, rec.Body)
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package rip
+
+import (
+	"context"
+	"time"
+
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// now is the clock of the entity timestamps and of the idempotent response expirations.
+var now = time.Now
+
+// timestampedCreate sets the `rip:"created_at"` and `rip:"updated_at"` fields of the entities
+// before creating them.
+func timestampedCreate[Ent any](create createFunc[Ent]) createFunc[Ent] {
+	return func(ctx context.Context, ent Ent) (Ent, error) {
+		setCreated(&ent, now())
+		return create(ctx, ent)
+	}
+}
+
+// timestampedUpdate sets the `rip:"updated_at"` field of the entities before updating them.
+func timestampedUpdate[Ent any](update updateFunc[Ent]) updateFunc[Ent] {
+	return func(ctx context.Context, ent Ent) (Ent, error) {
+		ripreflect.SetTaggedTime(&ent, ripreflect.TagUpdatedAt, now())
+		return update(ctx, ent)
+	}
+}
+
+// timestampedBatch sets the timestamps of the entities of the batch operations.
+func timestampedBatch[Ent any](batch batchFunc[Ent]) batchFunc[Ent] {
+	return func(ctx context.Context, ops []BatchOperation[Ent]) ([]BatchResult[Ent], error) {
+		t := now()
+		for i, op := range ops {
+			switch op.Action {
+			case BatchCreate:
+				setCreated(&ops[i].Entity, t)
+			case BatchUpdate:
+				ripreflect.SetTaggedTime(&ops[i].Entity, ripreflect.TagUpdatedAt, t)
+			}
+		}
+
+		return batch(ctx, ops)
+	}
+}
+
+// setCreated sets the creation and the last modification dates of a new entity.
+func setCreated[Ent any](ent *Ent, t time.Time) {
+	ripreflect.SetTaggedTime(ent, ripreflect.TagCreatedAt, t)
+	ripreflect.SetTaggedTime(ent, ripreflect.TagUpdatedAt, t)
+}
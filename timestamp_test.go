@@ -0,0 +1,158 @@
+package rip
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+type note struct {
+	ID        string     `json:"id"`
+	Text      string     `json:"text"`
+	CreatedAt time.Time  `json:"created_at" rip:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at" rip:"updated_at"`
+}
+
+func TestTimestamps(t *testing.T) {
+	clock := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	np := newMemoryProvider[note]()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/notes/", np, WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	do := func(t *testing.T, method, path, contentType, body string) (*http.Response, note) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+		panicErr(t, err)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := s.Client().Do(req)
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		panicErr(t, err)
+
+		var n note
+		if resp.StatusCode < http.StatusBadRequest && strings.HasPrefix(string(b), "{") {
+			err = json.Unmarshal(b, &n)
+			panicErr(t, err)
+		}
+
+		return resp, n
+	}
+
+	stored := func(t *testing.T, id string) note {
+		t.Helper()
+
+		n, err := np.Get(context.Background(), id)
+		panicErr(t, err)
+		return n
+	}
+
+	checkTimestamps := func(t *testing.T, n note, createdAt, updatedAt time.Time) {
+		t.Helper()
+
+		if !n.CreatedAt.Equal(createdAt) {
+			t.Fatal("bad created_at:", n.CreatedAt)
+		}
+		if n.UpdatedAt == nil || !n.UpdatedAt.Equal(updatedAt) {
+			t.Fatal("bad updated_at:", n.UpdatedAt)
+		}
+	}
+
+	created := clock
+
+	t.Run("create", func(t *testing.T) {
+		resp, n := do(t, http.MethodPost, "/notes/", "application/json", `{"id": "1", "text": "hello", "created_at": "2000-01-01T00:00:00Z", "updated_at": "2000-01-01T00:00:00Z"}`)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatal("status code is not 201:", resp.StatusCode)
+		}
+
+		checkTimestamps(t, n, created, created)
+		checkTimestamps(t, stored(t, "1"), created, created)
+		if resp.Header.Get("Last-Modified") != "Fri, 01 Mar 2024 12:00:00 GMT" {
+			t.Fatal("bad Last-Modified:", resp.Header.Get("Last-Modified"))
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		clock = clock.Add(time.Hour)
+
+		resp, n := do(t, http.MethodPut, "/notes/1", "application/json", `{"text": "hello world", "created_at": "2000-01-01T00:00:00Z"}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+
+		checkTimestamps(t, n, created, clock)
+		checkTimestamps(t, stored(t, "1"), created, clock)
+		if resp.Header.Get("Last-Modified") != "Fri, 01 Mar 2024 13:00:00 GMT" {
+			t.Fatal("bad Last-Modified:", resp.Header.Get("Last-Modified"))
+		}
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		clock = clock.Add(time.Hour)
+
+		resp, n := do(t, http.MethodPatch, "/notes/1", MergePatchMimeType, `{"text": "bye", "updated_at": null}`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+
+		checkTimestamps(t, n, created, clock)
+		checkTimestamps(t, stored(t, "1"), created, clock)
+	})
+
+	t.Run("update field", func(t *testing.T) {
+		clock = clock.Add(time.Hour)
+
+		resp, _ := do(t, http.MethodPut, "/notes/1/text", "application/json", `"again"`)
+		if resp.StatusCode != http.StatusNoContent {
+			t.Fatal("status code is not 204:", resp.StatusCode)
+		}
+
+		checkTimestamps(t, stored(t, "1"), created, clock)
+	})
+
+	t.Run("update timestamp field", func(t *testing.T) {
+		resp, _ := do(t, http.MethodPut, "/notes/1/created_at", "application/json", `"2000-01-01T00:00:00Z"`)
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatal("status code is not 400:", resp.StatusCode)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		clock = clock.Add(time.Hour)
+
+		resp, _ := do(t, http.MethodPost, "/notes/_batch", "application/json", `[{"action": "create", "entity": {"id": "2", "created_at": "2000-01-01T00:00:00Z"}}, {"action": "update", "entity": {"id": "1", "text": "batch"}}]`)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatal("status code is not 200:", resp.StatusCode)
+		}
+
+		checkTimestamps(t, stored(t, "2"), clock, clock)
+		checkTimestamps(t, stored(t, "1"), created, clock)
+	})
+
+	t.Run("openapi", func(t *testing.T) {
+		route := NewEntityRoute("/notes/", np, WithCodecs(jsoncodec.Codec))
+		schema := route.OpenAPISchema().Components.Schemas["note"].Value
+
+		if !schema.Properties["created_at"].Value.ReadOnly || !schema.Properties["updated_at"].Value.ReadOnly {
+			t.Fatal("the timestamps are not read-only")
+		}
+	})
+}
@@ -0,0 +1,460 @@
+package rip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/dolanor/rip/internal/ripreflect"
+)
+
+// Validator can be implemented by an entity to validate itself, for the constraints that can not
+// be described with the `validate` struct tags.
+// It is called when the struct tags constraints are satisfied, before the entity is sent to the
+// entity provider.
+// The error is answered with 422 Unprocessable Entity. It can be an [Error], [Errors], or
+// errors joined with [errors.Join], to point to the invalid fields with their Source.Pointer.
+type Validator interface {
+	Validate() error
+}
+
+// validateFunc validates an entity before it is created or updated.
+type validateFunc[Ent any] func(ent Ent) error
+
+// newValidator returns the validation of Ent, from the `validate` struct tags of its fields:
+//
+//	required     : the field is not a zero value
+//	min=N, max=N : the number is at least or at most N, or the string, slice or map length is
+//	len=N        : the string, slice or map length is N
+//	enum=a|b|c   : the value is a, b or c
+//	email        : the string is an email address
+//	url          : the string is an absolute URL
+//	pattern=RE   : the string matches the regular expression RE. It is the last rule of the tag, as RE can contain commas
+//
+// e.g.:
+//
+//	Name string `json:"name" validate:"required,min=2,max=50"`
+//
+// The format rules (email, url and pattern) are not checked on empty strings, unless they are required.
+// The fields of nested structs, and the structs in slices, are validated too.
+// The entity is then validated by its Validate method if it implements [Validator].
+//
+// It panics if a `validate` struct tag is invalid, so it fails when the route is created.
+func newValidator[Ent any]() validateFunc[Ent] {
+	rules, err := structRulesOf(reflect.TypeFor[Ent](), map[reflect.Type]*structRules{})
+	if err != nil {
+		panic(fmt.Sprintf("validate %s: %v", reflect.TypeFor[Ent](), err))
+	}
+
+	return func(ent Ent) error {
+		var errs []Error
+		if rules != nil {
+			rules.validate(reflect.ValueOf(ent), "", &errs)
+		}
+		if len(errs) > 0 {
+			return Errors{Errors: errs}
+		}
+
+		return validateEntity(ent)
+	}
+}
+
+// validateEntity validates ent with its Validate method, if it implements [Validator].
+func validateEntity[Ent any](ent Ent) error {
+	v, ok := any(ent).(Validator)
+	if !ok {
+		v, ok = any(&ent).(Validator)
+	}
+	if !ok {
+		return nil
+	}
+
+	err := v.Validate()
+	if err == nil {
+		return nil
+	}
+
+	return validationErrors(err)
+}
+
+// validationErrors converts an error from a [Validator] to [Errors], with the 422 status code by default.
+func validationErrors(err error) Errors {
+	var errs Errors
+	if errors.As(err, &errs) {
+		errs.Errors = slices.Clone(errs.Errors)
+	} else if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range joined.Unwrap() {
+			errs.Errors = append(errs.Errors, validationErrors(err).Errors...)
+		}
+	} else {
+		var e Error
+		if !errors.As(err, &e) {
+			e = Error{
+				Detail: err.Error(),
+				Source: extractErrorsSource(err),
+			}
+		}
+		errs.Errors = append(errs.Errors, e)
+	}
+
+	for i := range errs.Errors {
+		if errs.Errors[i].Status == 0 {
+			errs.Errors[i].Status = http.StatusUnprocessableEntity
+		}
+	}
+
+	return errs
+}
+
+// validatedCreate validates the entities before creating them.
+func validatedCreate[Ent any](create createFunc[Ent], validate validateFunc[Ent]) createFunc[Ent] {
+	return func(ctx context.Context, ent Ent) (Ent, error) {
+		err := validate(ent)
+		if err != nil {
+			return ent, err
+		}
+
+		return create(ctx, ent)
+	}
+}
+
+// validatedUpdate validates the entities before updating them.
+func validatedUpdate[Ent any](update updateFunc[Ent], validate validateFunc[Ent]) updateFunc[Ent] {
+	return func(ctx context.Context, ent Ent) (Ent, error) {
+		err := validate(ent)
+		if err != nil {
+			return ent, err
+		}
+
+		return update(ctx, ent)
+	}
+}
+
+// validatedBatch validates the entities of the batch operations before running them.
+// The errors point to the invalid fields of the operations, e.g. /2/entity/name.
+func validatedBatch[Ent any](batch batchFunc[Ent], validate validateFunc[Ent]) batchFunc[Ent] {
+	return func(ctx context.Context, ops []BatchOperation[Ent]) ([]BatchResult[Ent], error) {
+		var errs Errors
+		for i, op := range ops {
+			if op.Action != BatchCreate && op.Action != BatchUpdate {
+				continue
+			}
+
+			err := validate(op.Entity)
+			if err == nil {
+				continue
+			}
+
+			for _, e := range validationErrors(err).Errors {
+				e.Source.Pointer = "/" + strconv.Itoa(i) + "/entity" + e.Source.Pointer
+				errs.Errors = append(errs.Errors, e)
+			}
+		}
+
+		if len(errs.Errors) > 0 {
+			return nil, errs
+		}
+
+		return batch(ctx, ops)
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// structRules are the validation rules of the fields of a struct.
+type structRules struct {
+	fields []fieldRules
+}
+
+// fieldRules are the validation rules of a struct field, from its `validate` struct tag.
+type fieldRules struct {
+	index int
+
+	// name is the name of the field in the request document, for the error pointer.
+	name string
+
+	required bool
+	min, max *float64
+	length   *int
+	enum     []string
+	email    bool
+	url      bool
+	pattern  *regexp.Regexp
+
+	// nested are the rules of the struct field, or of the structs of the slice field.
+	nested *structRules
+}
+
+// structRulesOf parses the validation rules of the struct type t.
+// seen holds the rules of the types already parsed, for recursive types.
+func structRulesOf(t reflect.Type, seen map[reflect.Type]*structRules) (*structRules, error) {
+	t, ok := ripreflect.StructType(t)
+	if !ok || t == timeType {
+		return nil, nil
+	}
+
+	if rules, ok := seen[t]; ok {
+		return rules, nil
+	}
+
+	rules := &structRules{}
+	seen[t] = rules
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || ripreflect.IsReadOnly(f) {
+			// the server sets the read-only fields, not the clients
+			continue
+		}
+
+		fr, err := parseFieldRules(f)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+		fr.index = i
+
+		elemType := f.Type
+		for elemType.Kind() == reflect.Pointer {
+			elemType = elemType.Elem()
+		}
+		if elemType.Kind() == reflect.Slice || elemType.Kind() == reflect.Array {
+			elemType = elemType.Elem()
+		}
+
+		fr.nested, err = structRulesOf(elemType, seen)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", f.Name, err)
+		}
+
+		rules.fields = append(rules.fields, fr)
+	}
+
+	return rules, nil
+}
+
+// parseFieldRules parses the `validate` struct tag of f.
+func parseFieldRules(f reflect.StructField) (fieldRules, error) {
+	fr := fieldRules{
+		name: ripreflect.JSONName(f),
+	}
+
+	t := f.Type
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	tag := f.Tag.Get("validate")
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "pattern=") {
+			// a pattern can contain commas, so it ends the tag
+			rule, tag = tag, ""
+		} else {
+			rule, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, value, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			fr.required = true
+		case "min", "max":
+			if !hasLength(t) && !isNumber(t) {
+				return fr, fmt.Errorf("%s rule on a %s", name, t)
+			}
+
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fr, fmt.Errorf("%s rule: %q is not a number", name, value)
+			}
+
+			if name == "min" {
+				fr.min = &n
+			} else {
+				fr.max = &n
+			}
+		case "len":
+			if !hasLength(t) {
+				return fr, fmt.Errorf("len rule on a %s", t)
+			}
+
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fr, fmt.Errorf("len rule: %q is not an integer", value)
+			}
+			fr.length = &n
+		case "enum":
+			fr.enum = strings.Split(value, "|")
+		case "email", "url", "pattern":
+			if t.Kind() != reflect.String {
+				return fr, fmt.Errorf("%s rule on a %s", name, t)
+			}
+
+			switch name {
+			case "email":
+				fr.email = true
+			case "url":
+				fr.url = true
+			case "pattern":
+				re, err := regexp.Compile(value)
+				if err != nil {
+					return fr, fmt.Errorf("pattern rule: %w", err)
+				}
+				fr.pattern = re
+			}
+		case "":
+		default:
+			return fr, fmt.Errorf("unknown rule %q", name)
+		}
+	}
+
+	return fr, nil
+}
+
+func hasLength(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNumber(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func numberValue(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	default:
+		return v.Float()
+	}
+}
+
+// validate validates the struct v, and adds an error per invalid field to errs.
+// pointer is the JSON pointer of v in the request document.
+func (sr *structRules) validate(v reflect.Value, pointer string, errs *[]Error) {
+	v = derefValue(v)
+	if !v.IsValid() {
+		return
+	}
+
+	for _, fr := range sr.fields {
+		fv := v.Field(fr.index)
+		fieldPointer := pointer + "/" + fr.name
+
+		msg := fr.check(fv)
+		if msg != "" {
+			*errs = append(*errs, Error{
+				Status: http.StatusUnprocessableEntity,
+				Title:  "invalid field",
+				Detail: fr.name + " " + msg,
+				Source: ErrorSource{
+					Pointer: fieldPointer,
+				},
+			})
+			continue
+		}
+
+		if fr.nested == nil {
+			continue
+		}
+
+		fv = derefValue(fv)
+		switch fv.Kind() {
+		case reflect.Struct:
+			fr.nested.validate(fv, fieldPointer, errs)
+		case reflect.Slice, reflect.Array:
+			for i := 0; i < fv.Len(); i++ {
+				fr.nested.validate(fv.Index(i), fieldPointer+"/"+strconv.Itoa(i), errs)
+			}
+		}
+	}
+}
+
+// check returns why the field value v is invalid, or an empty string if it is valid.
+func (fr fieldRules) check(v reflect.Value) string {
+	zero := v.IsZero()
+	if zero && fr.required {
+		return "is required"
+	}
+
+	v = derefValue(v)
+	if !v.IsValid() {
+		// a nil pointer, the optional value is absent
+		return ""
+	}
+
+	length := -1
+	switch v.Kind() {
+	case reflect.String:
+		length = utf8.RuneCountInString(v.String())
+	case reflect.Slice, reflect.Array, reflect.Map:
+		length = v.Len()
+	}
+
+	switch {
+	case fr.length != nil && length != *fr.length:
+		return fmt.Sprintf("must have a length of %d", *fr.length)
+	case fr.min != nil && length >= 0 && float64(length) < *fr.min:
+		return "must have a length of at least " + formatNumber(*fr.min)
+	case fr.max != nil && length >= 0 && float64(length) > *fr.max:
+		return "must have a length of at most " + formatNumber(*fr.max)
+	case fr.min != nil && length < 0 && numberValue(v) < *fr.min:
+		return "must be at least " + formatNumber(*fr.min)
+	case fr.max != nil && length < 0 && numberValue(v) > *fr.max:
+		return "must be at most " + formatNumber(*fr.max)
+	case fr.enum != nil && !slices.Contains(fr.enum, fmt.Sprint(v.Interface())):
+		return "must be one of " + strings.Join(fr.enum, ", ")
+	}
+
+	if v.Kind() != reflect.String || zero {
+		// the format rules don't apply to an empty optional string
+		return ""
+	}
+
+	s := v.String()
+	if fr.email {
+		addr, err := mail.ParseAddress(s)
+		if err != nil || addr.Address != s {
+			return "must be an email address"
+		}
+	}
+
+	if fr.url {
+		u, err := url.Parse(s)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be a URL"
+		}
+	}
+
+	if fr.pattern != nil && !fr.pattern.MatchString(s) {
+		return "must match the pattern " + fr.pattern.String()
+	}
+
+	return ""
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
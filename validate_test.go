@@ -0,0 +1,230 @@
+package rip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	jsoncodec "github.com/dolanor/rip/encoding/json"
+)
+
+type memberAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type member struct {
+	ID      string          `json:"id"`
+	Name    string          `json:"name" validate:"required,min=2,max=20"`
+	Email   string          `json:"email" validate:"email"`
+	Age     int             `json:"age" validate:"min=18,max=130"`
+	Role    string          `json:"role" validate:"enum=admin|user"`
+	Website string          `json:"website" validate:"url"`
+	Code    string          `json:"code" validate:"pattern=^[A-Z]{2,3}$"`
+	Address *memberAddress  `json:"address"`
+	Friends []memberAddress `json:"friends"`
+}
+
+func (m member) Validate() error {
+	if m.Role == "admin" && m.Website == "" {
+		return Error{
+			Detail: "an admin needs a website",
+			Source: ErrorSource{
+				Pointer: "/website",
+			},
+		}
+	}
+
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	mp := newMemoryProvider(member{ID: "1", Name: "Jane", Age: 40, Role: "user"})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(HandleEntities("/members/", mp, WithCodecs(jsoncodec.Codec)))
+	mux.HandleFunc("/join", Handle(http.MethodPost, func(ctx context.Context, m member) (member, error) {
+		return m, nil
+	}, WithCodecs(jsoncodec.Codec)))
+	s := httptest.NewServer(mux)
+	defer s.Close()
+
+	cases := map[string]struct {
+		method   string
+		path     string
+		body     string
+		status   int
+		pointers []string
+	}{
+		"valid": {
+			method: http.MethodPost,
+			path:   "/members/",
+			body:   `{"id": "2", "name": "Joe", "email": "joe@example.com", "age": 30, "role": "user", "code": "FR"}`,
+			status: http.StatusCreated,
+		},
+		"invalid fields": {
+			method:   http.MethodPost,
+			path:     "/members/",
+			body:     `{"id": "3", "email": "joe", "age": 12, "role": "root", "website": "example.com", "code": "fr", "address": {}, "friends": [{"city": "Paris"}, {}]}`,
+			status:   http.StatusUnprocessableEntity,
+			pointers: []string{"/name", "/email", "/age", "/role", "/website", "/code", "/address/city", "/friends/1/city"},
+		},
+		"too long": {
+			method:   http.MethodPost,
+			path:     "/members/",
+			body:     `{"id": "3", "name": "Joseph-Marie-Alexandre", "age": 30, "role": "user"}`,
+			status:   http.StatusUnprocessableEntity,
+			pointers: []string{"/name"},
+		},
+		"zero value": {
+			method:   http.MethodPost,
+			path:     "/members/",
+			body:     `{"id": "3", "name": "Joe", "role": "user"}`,
+			status:   http.StatusUnprocessableEntity,
+			pointers: []string{"/age"},
+		},
+		"validate method": {
+			method:   http.MethodPost,
+			path:     "/members/",
+			body:     `{"id": "3", "name": "Joe", "age": 30, "role": "admin"}`,
+			status:   http.StatusUnprocessableEntity,
+			pointers: []string{"/website"},
+		},
+		"update": {
+			method:   http.MethodPut,
+			path:     "/members/1",
+			body:     `{"name": "J", "age": 40, "role": "user"}`,
+			status:   http.StatusUnprocessableEntity,
+			pointers: []string{"/name"},
+		},
+		"update field": {
+			method:   http.MethodPut,
+			path:     "/members/1/age",
+			body:     `3`,
+			status:   http.StatusUnprocessableEntity,
+			pointers: []string{"/age"},
+		},
+		"patch": {
+			method:   http.MethodPatch,
+			path:     "/members/1",
+			body:     `{"email": "jane"}`,
+			status:   http.StatusUnprocessableEntity,
+			pointers: []string{"/email"},
+		},
+		"handle": {
+			method:   http.MethodPost,
+			path:     "/join",
+			body:     `{"name": "Joe", "age": 3, "role": "user"}`,
+			status:   http.StatusUnprocessableEntity,
+			pointers: []string{"/age"},
+		},
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(c.method, s.URL+c.path, strings.NewReader(c.body))
+			panicErr(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			if c.method == http.MethodPatch {
+				req.Header.Set("Content-Type", MergePatchMimeType)
+			}
+			req.Header.Set("Accept", "application/json")
+
+			resp, err := s.Client().Do(req)
+			panicErr(t, err)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != c.status {
+				t.Fatalf("status code is not %d: %d", c.status, resp.StatusCode)
+			}
+
+			if c.status != http.StatusUnprocessableEntity {
+				return
+			}
+
+			var errs Errors
+			err = json.NewDecoder(resp.Body).Decode(&errs)
+			panicErr(t, err)
+
+			var pointers []string
+			for _, e := range errs.Errors {
+				if e.Status != http.StatusUnprocessableEntity {
+					t.Errorf("error status is not 422: %d", e.Status)
+				}
+				pointers = append(pointers, e.Source.Pointer)
+			}
+			if !reflect.DeepEqual(pointers, c.pointers) {
+				t.Fatalf("error pointers are not\n%v:\n%v", c.pointers, pointers)
+			}
+		})
+	}
+
+	t.Run("batch", func(t *testing.T) {
+		resp, err := s.Client().Post(s.URL+"/members/_batch", "application/json", strings.NewReader(`[{"action": "create", "entity": {"id": "4"}}]`))
+		panicErr(t, err)
+		defer resp.Body.Close()
+
+		var results []BatchResult[member]
+		err = json.NewDecoder(resp.Body).Decode(&results)
+		panicErr(t, err)
+		if len(results) != 1 || results[0].Status != http.StatusUnprocessableEntity {
+			t.Fatal("invalid entity result is not 422:", results)
+		}
+	})
+
+	t.Run("openapi", func(t *testing.T) {
+		route := NewEntityRoute("/members/", mp, WithCodecs(jsoncodec.Codec))
+		schema := route.OpenAPISchema().Components.Schemas["member"].Value
+
+		if !slices.Equal(schema.Required, []string{"name"}) {
+			t.Fatal("bad required fields:", schema.Required)
+		}
+
+		name := schema.Properties["name"].Value
+		if name.MinLength != 2 || name.MaxLength == nil || *name.MaxLength != 20 {
+			t.Fatal("bad name length:", name.MinLength, name.MaxLength)
+		}
+
+		age := schema.Properties["age"].Value
+		if age.Min == nil || *age.Min != 18 || age.Max == nil || *age.Max != 130 {
+			t.Fatal("bad age range:", age.Min, age.Max)
+		}
+
+		if !reflect.DeepEqual(schema.Properties["role"].Value.Enum, []any{"admin", "user"}) {
+			t.Fatal("bad role enum:", schema.Properties["role"].Value.Enum)
+		}
+
+		if schema.Properties["email"].Value.Format != "email" {
+			t.Fatal("bad email format:", schema.Properties["email"].Value.Format)
+		}
+
+		if schema.Properties["code"].Value.Pattern != "^[A-Z]{2,3}$" {
+			t.Fatal("bad code pattern:", schema.Properties["code"].Value.Pattern)
+		}
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		type invalid struct {
+			Age int `validate:"min=young"`
+		}
+
+		defer func() {
+			if recover() == nil {
+				t.Fatal("an invalid validate tag does not panic")
+			}
+		}()
+		newValidator[invalid]()
+	})
+
+	t.Run("joined errors", func(t *testing.T) {
+		errs := validationErrors(errors.Join(errors.New("a"), Error{Status: http.StatusConflict, Detail: "b"}))
+		if len(errs.Errors) != 2 || errs.Errors[0].Status != http.StatusUnprocessableEntity || errs.Errors[1].Status != http.StatusConflict {
+			t.Fatal("bad joined errors:", errs)
+		}
+	})
+}